@@ -31,12 +31,20 @@ type CompositeResourceDefinitionSpec struct {
 	// Group specifies the API group of the defined composite resource.
 	// Composite resources are served under `/apis/<group>/...`. Must match the
 	// name of the XRD (in the form `<names.plural>.<group>`).
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
 	Group string `json:"group"`
 
 	// Names specifies the resource and kind names of the defined composite
 	// resource.
 	Names extv1.CustomResourceDefinitionNames `json:"names"`
 
+	// Scope of the defined composite resource; either Cluster or Namespaced.
+	// Composite resources are cluster scoped by default. Namespaced composite
+	// resources cannot be claimed, and so must not specify claimNames.
+	// +optional
+	// +kubebuilder:validation:Enum=Cluster;Namespaced
+	Scope extv1.ResourceScope `json:"scope,omitempty"`
+
 	// ClaimNames specifies the names of an optional composite resource claim.
 	// When claim names are specified Crossplane will create a namespaced
 	// 'composite resource claim' CRD that corresponds to the defined composite
@@ -48,6 +56,11 @@ type CompositeResourceDefinitionSpec struct {
 	// +optional
 	ClaimNames *extv1.CustomResourceDefinitionNames `json:"claimNames,omitempty"`
 
+	// Description of the defined composite resource and, if offered, its
+	// claim. It is shown by 'kubectl explain'.
+	// +optional
+	Description string `json:"description,omitempty"`
+
 	// ConnectionSecretKeys is the list of keys that will be exposed to the end
 	// user of the defined kind.
 	// +optional
@@ -64,6 +77,38 @@ type CompositeResourceDefinitionSpec struct {
 	// +immutable
 	EnforcedCompositionRef *v1alpha1.Reference `json:"enforcedCompositionRef,omitempty"`
 
+	// AdditionalPrinterColumns specifies additional columns returned in Table
+	// output for all versions of the defined composite resource and, if
+	// offered, its claim. Columns specified by a version take precedence over
+	// equivalently named columns specified here. Both take precedence over
+	// equivalently named default columns, for example COMPOSITION.
+	// +optional
+	AdditionalPrinterColumns []extv1.CustomResourceColumnDefinition `json:"additionalPrinterColumns,omitempty"`
+
+	// OmitAgeColumn omits the AGE column that is otherwise appended to the
+	// printer columns of the defined composite resource and its claim.
+	// +optional
+	OmitAgeColumn bool `json:"omitAgeColumn,omitempty"`
+
+	// PausedReconcileField adds a boolean spec.pausedReconcile field that may
+	// be used to pause reconciliation of a defined composite resource, and a
+	// corresponding PAUSED printer column.
+	// +optional
+	PausedReconcileField bool `json:"pausedReconcileField,omitempty"`
+
+	// Scale configures a scale subresource for the defined composite
+	// resource, allowing it to be scaled by 'kubectl scale'. The replica paths
+	// must refer to integer fields of the schema of each version, and the
+	// label selector path, if any, must refer to a string field.
+	// +optional
+	Scale *extv1.CustomResourceSubresourceScale `json:"scale,omitempty"`
+
+	// Conversion configures how the versions of the defined composite
+	// resource and, if offered, its claim are converted. Versions are not
+	// converted by default.
+	// +optional
+	Conversion *CompositeResourceConversion `json:"conversion,omitempty"`
+
 	// Versions is the list of all API versions of the defined composite
 	// resource. Version names are used to compute the order in which served
 	// versions are listed in API discovery. If the version string is
@@ -77,6 +122,7 @@ type CompositeResourceDefinitionSpec struct {
 	// v10beta3, v3beta1, v12alpha1, v11alpha2, foo1, foo10. Note that all
 	// versions must have identical schemas; Crossplane does not currently
 	// support conversion between different version schemas.
+	// +kubebuilder:validation:MinItems=1
 	Versions []CompositeResourceDefinitionVersion `json:"versions"`
 }
 
@@ -85,6 +131,7 @@ type CompositeResourceDefinitionVersion struct {
 	// Name of this version, e.g. “v1”, “v2beta1”, etc. Composite resources are
 	// served under this version at `/apis/<group>/<version>/...` if `served` is
 	// true.
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
 	Name string `json:"name"`
 
 	// Referenceable specifies that this version may be referenced by a
@@ -123,6 +170,25 @@ type CompositeResourceValidation struct {
 	OpenAPIV3Schema runtime.RawExtension `json:"openAPIV3Schema,omitempty"`
 }
 
+// CompositeResourceConversion configures how the versions of a composite
+// resource are converted.
+type CompositeResourceConversion struct {
+	// Strategy specifies how versions are converted. None changes only the
+	// apiVersion of a resource. Webhook calls the configured service.
+	// +kubebuilder:validation:Enum=None;Webhook
+	Strategy extv1.ConversionStrategyType `json:"strategy"`
+
+	// Service references the service that serves the conversion webhook.
+	// Required when the strategy is Webhook.
+	// +optional
+	Service *extv1.ServiceReference `json:"service,omitempty"`
+
+	// CABundle is a PEM encoded CA bundle which will be used to validate the
+	// conversion webhook's server certificate.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+}
+
 // CompositeResourceDefinitionStatus shows the observed state of the definition.
 type CompositeResourceDefinitionStatus struct {
 	v1alpha1.ConditionedStatus `json:",inline"`
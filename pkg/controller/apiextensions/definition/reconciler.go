@@ -187,7 +187,9 @@ func NewReconciler(mgr manager.Manager, opts ...ReconcilerOption) *Reconciler {
 		},
 
 		composite: definition{
-			CRDRenderer:      CRDRenderFn(xcrd.ForCompositeResource),
+			CRDRenderer: CRDRenderFn(func(d *v1beta1.CompositeResourceDefinition) (*extv1.CustomResourceDefinition, error) {
+				return xcrd.ForCompositeResource(d)
+			}),
 			ControllerEngine: controller.NewEngine(mgr),
 			Finalizer:        resource.NewAPIFinalizer(kube, finalizer),
 		},
@@ -24,12 +24,14 @@ package xcrd
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
-	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/crossplane/crossplane/apis/apiextensions/v1beta1"
 )
@@ -40,20 +42,62 @@ const (
 	CategoryComposite = "composite"
 )
 
+// LabelKeyXRD is the label used to record the name of the
+// CompositeResourceDefinition a CustomResourceDefinition was derived from.
+const LabelKeyXRD = "crossplane.io/xrd"
+
+// AnnotationKeyXRDUID is the annotation used to record the UID of the
+// CompositeResourceDefinition a CustomResourceDefinition was derived from.
+const AnnotationKeyXRDUID = "apiextensions.crossplane.io/xrd-uid"
+
+// AnnotationKeySpecFields is the annotation used to record the comma separated
+// top-level spec fields of a CustomResourceDefinition. See
+// WithSpecFieldsAnnotation.
+const AnnotationKeySpecFields = "apiextensions.crossplane.io/spec-fields"
+
+// Annotations that may be used to override which versions of a
+// CompositeResourceDefinition are served and stored. See
+// WithVersionFlagsFromAnnotations.
+const (
+	AnnotationKeyServedVersions = "apiextensions.crossplane.io/served-versions"
+	AnnotationKeyStorageVersion = "apiextensions.crossplane.io/storage-version"
+)
+
 const (
-	errGetSpecProps            = "cannot get spec properties from validation schema"
 	errParseValidation         = "cannot parse validation schema"
+	errFmtSchemaRootType       = "schema must be of type object at its root, not %q"
+	errFmtAdditionalProperties = "%s must not specify additionalProperties; Crossplane adds properties to it"
 	errInvalidClaimNames       = "invalid resource claim names"
 	errMissingClaimNames       = "missing names"
 	errFmtConflictingClaimName = "%q conflicts with composite resource name"
+	errFmtConflictingClaimKind = "claim kind %q differs from composite resource kind %q only by case"
+	errFmtUnexpectedName       = "name %q must be %q (<names.plural>.<group>)"
+	errFmtReservedField        = "spec.%s is reserved; it is managed by Crossplane and must not be defined by the schema"
+	errFmtConversionStrategy   = "unknown conversion strategy %q; must be None or Webhook"
+	errConversionService       = "a conversion webhook requires a service name"
+	errFmtScope                = "unknown scope %q; must be Cluster or Namespaced"
+	errNamespacedClaim         = "a Namespaced composite resource cannot be claimed; claimNames must not be specified"
+	errFmtStorageIndex         = "storage version index %d is out of range"
+	errLossyField              = "is absent from the storage version schema, and would be dropped when stored"
 )
 
 // ForCompositeResource derives the CustomResourceDefinition for a composite
 // resource from the supplied CompositeResourceDefinition.
-func ForCompositeResource(xrd *v1beta1.CompositeResourceDefinition) (*extv1.CustomResourceDefinition, error) {
+func ForCompositeResource(xrd *v1beta1.CompositeResourceDefinition, opts ...Option) (*extv1.CustomResourceDefinition, error) {
+	o := newOptions(opts...)
+
+	if err := validateName(xrd); err != nil {
+		return nil, err
+	}
+
+	scope, managed, err := compositeScope(xrd)
+	if err != nil {
+		return nil, err
+	}
+
 	crd := &extv1.CustomResourceDefinition{
 		Spec: extv1.CustomResourceDefinitionSpec{
-			Scope:    extv1.ClusterScoped,
+			Scope:    scope,
 			Group:    xrd.Spec.Group,
 			Names:    xrd.Spec.Names,
 			Versions: make([]extv1.CustomResourceDefinitionVersion, len(xrd.Spec.Versions)),
@@ -61,54 +105,131 @@ func ForCompositeResource(xrd *v1beta1.CompositeResourceDefinition) (*extv1.Cust
 	}
 
 	crd.SetName(xrd.GetName())
-	crd.SetLabels(xrd.GetLabels())
-	crd.SetAnnotations(xrd.GetAnnotations())
-	crd.SetOwnerReferences([]metav1.OwnerReference{meta.AsController(
-		meta.TypedReferenceTo(xrd, v1beta1.CompositeResourceDefinitionGroupVersionKind),
-	)})
+	crd.SetLabels(o.labels(xrd))
+	crd.SetAnnotations(o.annotations(xrd))
+	crd.SetOwnerReferences(o.ownerReferences(xrd))
+
+	crd.Spec.Names.Categories = withCategory(xrd.Spec.Names.Categories, CategoryComposite)
+	crd.Spec.Names.ShortNames = unique(xrd.Spec.Names.ShortNames)
 
-	crd.Spec.Names.Categories = append(crd.Spec.Names.Categories, CategoryComposite)
+	c, err := conversion(xrd)
+	if err != nil {
+		return nil, err
+	}
+	crd.Spec.Conversion = c
 
 	for i, vr := range xrd.Spec.Versions {
+		var enabled []extv1.CustomResourceColumnDefinition
+		if xrd.Spec.PausedReconcileField {
+			enabled = PausedReconcilePrinterColumns()
+		}
+		cols, err := o.printerColumns(CompositeResourcePrinterColumns(), enabled)
+		if err != nil {
+			return nil, err
+		}
+
 		crd.Spec.Versions[i] = extv1.CustomResourceDefinitionVersion{
 			Name:                     vr.Name,
 			Served:                   vr.Served,
 			Storage:                  vr.Referenceable,
-			AdditionalPrinterColumns: append(vr.AdditionalPrinterColumns, CompositeResourcePrinterColumns()...),
-			Schema: &extv1.CustomResourceValidation{
-				OpenAPIV3Schema: &extv1.JSONSchemaProps{
-					Type:       "object",
-					Properties: BaseProps(),
-				},
-			},
+			AdditionalPrinterColumns: withAgeColumn(xrd, mergePrinterColumns(cols, xrd.Spec.AdditionalPrinterColumns, vr.AdditionalPrinterColumns)),
 			Subresources: &extv1.CustomResourceSubresources{
 				Status: &extv1.CustomResourceSubresourceStatus{},
+				Scale:  xrd.Spec.Scale.DeepCopy(),
 			},
 		}
 
-		p, err := getSpecProps(vr.Schema)
+		if err := o.validateSchemaRoot(vr); err != nil {
+			return nil, errors.Wrapf(err, errFmtInvalidVersion, vr.Name)
+		}
+
+		var user runtime.RawExtension
+		if vr.Schema != nil {
+			user = vr.Schema.OpenAPIV3Schema
+		}
+		s, err := BuildSchema(&extv1.JSONSchemaProps{Type: "object", Properties: BaseProps()}, user)
 		if err != nil {
-			return nil, errors.Wrap(err, errGetSpecProps)
+			return nil, errors.Wrapf(err, errFmtInvalidVersion, vr.Name)
 		}
-		for k, v := range p {
-			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"].Properties[k] = v
+		reserved := o.reservedSpecProps(managed)
+		if xrd.Spec.PausedReconcileField {
+			for k, v := range PausedReconcileSpecProps() {
+				reserved[k] = v
+			}
+		}
+		if err := validateReservedFields(s.Properties["spec"].Properties, reserved); err != nil {
+			return nil, errors.Wrapf(err, errFmtInvalidVersion, vr.Name)
 		}
-		for k, v := range CompositeResourceSpecProps() {
+		spec := s.Properties["spec"]
+		spec.Required = withoutReserved(spec.Required, reserved)
+		s.Properties["spec"] = spec
+		if xrd.Spec.Description != "" {
+			s.Description = xrd.Spec.Description
+		}
+		crd.Spec.Versions[i].Schema = &extv1.CustomResourceValidation{OpenAPIV3Schema: s}
+		for k, v := range reserved {
 			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"].Properties[k] = v
 		}
 		for k, v := range CompositeResourceStatusProps() {
 			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"].Properties[k] = v
 		}
+		for k, v := range o.statusProps() {
+			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"].Properties[k] = v
+		}
+		o.transform(&crd.Spec.Versions[i])
+	}
+
+	if err := o.versionFlags(xrd, crd); err != nil {
+		return nil, err
+	}
+
+	o.specFieldsAnnotation(crd)
+
+	if err := o.validate(crd); err != nil {
+		return nil, err
 	}
 
 	return crd, nil
 }
 
+// ForCompositeResourceShell derives a minimal CustomResourceDefinition for a
+// composite resource from the supplied CompositeResourceDefinition. The shell
+// includes only the fields Crossplane manages, omitting the schema and printer
+// columns of each version. It may be used to quickly establish a composite
+// resource type, so that informers may be started, but must be replaced by the
+// CustomResourceDefinition derived by ForCompositeResource.
+func ForCompositeResourceShell(xrd *v1beta1.CompositeResourceDefinition, opts ...Option) (*extv1.CustomResourceDefinition, error) {
+	shell := xrd.DeepCopy()
+	shell.Spec.AdditionalPrinterColumns = nil
+	shell.Spec.Scale = nil
+	for i := range shell.Spec.Versions {
+		shell.Spec.Versions[i].Schema = nil
+		shell.Spec.Versions[i].AdditionalPrinterColumns = nil
+	}
+	return ForCompositeResource(shell, opts...)
+}
+
 // ForCompositeResourceClaim derives the CustomResourceDefinition for a
 // composite resource claim from the supplied CompositeResourceDefinition.
-func ForCompositeResourceClaim(xrd *v1beta1.CompositeResourceDefinition) (*extv1.CustomResourceDefinition, error) {
-	if err := validateClaimNames(xrd); err != nil {
-		return nil, errors.Wrap(err, errInvalidClaimNames)
+func ForCompositeResourceClaim(xrd *v1beta1.CompositeResourceDefinition, opts ...Option) (*extv1.CustomResourceDefinition, error) {
+	o := newOptions(opts...)
+
+	// The name of a claim CRD is always <claimNames.plural>.<group>, but the
+	// group must still agree with the name of the definition.
+	if err := validateName(xrd); err != nil {
+		return nil, err
+	}
+
+	if errs := validateClaimNames(xrd); len(errs) > 0 {
+		return nil, errors.Wrap(errs.ToAggregate(), errInvalidClaimNames)
+	}
+
+	if _, _, err := compositeScope(xrd); err != nil {
+		return nil, err
+	}
+
+	if o.strict {
+		o.checkSwappedNames(xrd.Spec.Names, *xrd.Spec.ClaimNames)
 	}
 
 	crd := &extv1.CustomResourceDefinition{
@@ -121,89 +242,378 @@ func ForCompositeResourceClaim(xrd *v1beta1.CompositeResourceDefinition) (*extv1
 	}
 
 	crd.SetName(xrd.Spec.ClaimNames.Plural + "." + xrd.Spec.Group)
-	crd.SetLabels(xrd.GetLabels())
-	crd.SetAnnotations(xrd.GetAnnotations())
-	crd.SetOwnerReferences([]metav1.OwnerReference{meta.AsController(
-		meta.TypedReferenceTo(xrd, v1beta1.CompositeResourceDefinitionGroupVersionKind),
-	)})
+	crd.SetLabels(o.labels(xrd))
+	crd.SetAnnotations(o.annotations(xrd))
+	crd.SetOwnerReferences(o.ownerReferences(xrd))
+
+	// Claims inherit the categories of their composite resource, unless their
+	// own categories are specified.
+	categories := xrd.Spec.ClaimNames.Categories
+	if len(categories) == 0 {
+		categories = xrd.Spec.Names.Categories
+	}
+	crd.Spec.Names.Categories = withCategory(categories, CategoryClaim)
+	crd.Spec.Names.ShortNames = unique(xrd.Spec.ClaimNames.ShortNames)
 
-	crd.Spec.Names.Categories = append(crd.Spec.Names.Categories, CategoryClaim)
+	c, err := conversion(xrd)
+	if err != nil {
+		return nil, err
+	}
+	crd.Spec.Conversion = c
 
 	for i, vr := range xrd.Spec.Versions {
+		cols, err := o.printerColumns(CompositeResourceClaimPrinterColumns(), nil)
+		if err != nil {
+			return nil, err
+		}
+
 		crd.Spec.Versions[i] = extv1.CustomResourceDefinitionVersion{
 			Name:                     vr.Name,
 			Served:                   vr.Served,
 			Storage:                  vr.Referenceable,
-			AdditionalPrinterColumns: append(vr.AdditionalPrinterColumns, CompositeResourceClaimPrinterColumns()...),
-			Schema: &extv1.CustomResourceValidation{
-				OpenAPIV3Schema: &extv1.JSONSchemaProps{
-					Type:       "object",
-					Properties: BaseProps(),
-				},
-			},
+			AdditionalPrinterColumns: withAgeColumn(xrd, mergePrinterColumns(cols, xrd.Spec.AdditionalPrinterColumns, vr.AdditionalPrinterColumns)),
 			Subresources: &extv1.CustomResourceSubresources{
 				Status: &extv1.CustomResourceSubresourceStatus{},
 			},
 		}
 
-		p, err := getSpecProps(vr.Schema)
+		if err := o.validateSchemaRoot(vr); err != nil {
+			return nil, errors.Wrapf(err, errFmtInvalidVersion, vr.Name)
+		}
+
+		var user runtime.RawExtension
+		if vr.Schema != nil {
+			user = vr.Schema.OpenAPIV3Schema
+		}
+		s, err := BuildSchema(&extv1.JSONSchemaProps{Type: "object", Properties: BaseProps()}, user)
 		if err != nil {
-			return nil, errors.Wrap(err, errGetSpecProps)
+			return nil, errors.Wrapf(err, errFmtInvalidVersion, vr.Name)
 		}
-		for k, v := range p {
-			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"].Properties[k] = v
+		reserved := o.reservedSpecProps(CompositeResourceClaimSpecProps())
+		if err := validateReservedFields(s.Properties["spec"].Properties, reserved); err != nil {
+			return nil, errors.Wrapf(err, errFmtInvalidVersion, vr.Name)
+		}
+		spec := s.Properties["spec"]
+		spec.Required = withoutReserved(spec.Required, reserved)
+		s.Properties["spec"] = spec
+		if xrd.Spec.Description != "" {
+			s.Description = xrd.Spec.Description
 		}
-		for k, v := range CompositeResourceClaimSpecProps() {
+		crd.Spec.Versions[i].Schema = &extv1.CustomResourceValidation{OpenAPIV3Schema: s}
+		for k, v := range reserved {
 			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"].Properties[k] = v
 		}
 		for k, v := range CompositeResourceStatusProps() {
 			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"].Properties[k] = v
 		}
+		for k, v := range o.statusProps() {
+			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"].Properties[k] = v
+		}
+		o.transform(&crd.Spec.Versions[i])
+	}
+
+	if err := o.versionFlags(xrd, crd); err != nil {
+		return nil, err
+	}
+
+	o.specFieldsAnnotation(crd)
+
+	if err := o.validate(crd); err != nil {
+		return nil, err
 	}
 
 	return crd, nil
 }
 
-func validateClaimNames(d *v1beta1.CompositeResourceDefinition) error {
+func validateName(d *v1beta1.CompositeResourceDefinition) error {
+	if n := d.Spec.Names.Plural + "." + d.Spec.Group; d.GetName() != n {
+		return errors.Errorf(errFmtUnexpectedName, d.GetName(), n)
+	}
+	return nil
+}
+
+// validateClaimNames returns an error for each of the claim names of the
+// supplied CompositeResourceDefinition that conflicts with its composite
+// resource names.
+func validateClaimNames(d *v1beta1.CompositeResourceDefinition) field.ErrorList {
+	p := field.NewPath("spec", "claimNames")
 	if d.Spec.ClaimNames == nil {
-		return errors.New(errMissingClaimNames)
+		return field.ErrorList{field.Required(p, errMissingClaimNames)}
 	}
 
-	if n := d.Spec.ClaimNames.Kind; n == d.Spec.Names.Kind {
-		return errors.Errorf(errFmtConflictingClaimName, n)
+	errs := field.ErrorList{}
+
+	switch n := d.Spec.ClaimNames.Kind; {
+	case n == d.Spec.Names.Kind:
+		errs = append(errs, field.Invalid(p.Child("kind"), n, fmt.Sprintf(errFmtConflictingClaimName, n)))
+	case strings.EqualFold(n, d.Spec.Names.Kind):
+		errs = append(errs, field.Invalid(p.Child("kind"), n, fmt.Sprintf(errFmtConflictingClaimKind, n, d.Spec.Names.Kind)))
 	}
 
-	if n := d.Spec.ClaimNames.Plural; n == d.Spec.Names.Plural {
-		return errors.Errorf(errFmtConflictingClaimName, n)
+	if n := d.Spec.ClaimNames.ListKind; n != "" && n == d.Spec.Names.ListKind {
+		errs = append(errs, field.Invalid(p.Child("listKind"), n, fmt.Sprintf(errFmtConflictingClaimName, n)))
 	}
 
 	if n := d.Spec.ClaimNames.Singular; n != "" && n == d.Spec.Names.Singular {
-		return errors.Errorf(errFmtConflictingClaimName, n)
+		errs = append(errs, field.Invalid(p.Child("singular"), n, fmt.Sprintf(errFmtConflictingClaimName, n)))
 	}
 
-	if n := d.Spec.ClaimNames.ListKind; n != "" && n == d.Spec.Names.ListKind {
-		return errors.Errorf(errFmtConflictingClaimName, n)
+	if n := d.Spec.ClaimNames.Plural; n == d.Spec.Names.Plural {
+		errs = append(errs, field.Invalid(p.Child("plural"), n, fmt.Sprintf(errFmtConflictingClaimName, n)))
+	}
+
+	shortNames := map[string]bool{}
+	for _, n := range d.Spec.Names.ShortNames {
+		shortNames[n] = true
+	}
+	for i, n := range d.Spec.ClaimNames.ShortNames {
+		if shortNames[n] {
+			errs = append(errs, field.Invalid(p.Child("shortNames").Index(i), n, fmt.Sprintf(errFmtConflictingClaimName, n)))
+		}
 	}
 
+	return errs
+}
+
+// validateReservedFields returns an error if the supplied spec props, which
+// are derived from the schema of a CompositeResourceDefinition, define any of
+// the supplied reserved spec props. Reserved props are managed by Crossplane,
+// and would otherwise silently replace those defined by the schema.
+func validateReservedFields(p, reserved map[string]extv1.JSONSchemaProps) error {
+	for _, k := range sortedKeys(reserved) {
+		if _, ok := p[k]; ok {
+			return errors.Errorf(errFmtReservedField, k)
+		}
+	}
 	return nil
 }
 
-func getSpecProps(v *v1beta1.CompositeResourceValidation) (map[string]extv1.JSONSchemaProps, error) {
-	if v == nil {
-		return nil, nil
+// withoutReserved returns the supplied required spec props, less any of the
+// supplied reserved spec props. Crossplane, not the schema of a
+// CompositeResourceDefinition, decides whether a reserved spec prop is
+// required.
+func withoutReserved(required []string, reserved map[string]extv1.JSONSchemaProps) []string {
+	var out []string
+	for _, r := range required {
+		if _, ok := reserved[r]; !ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// withCategory returns a copy of the supplied categories that includes the
+// supplied category.
+func withCategory(categories []string, category string) []string {
+	out := make([]string, 0, len(categories)+1)
+	for _, c := range categories {
+		if c != category {
+			out = append(out, c)
+		}
+	}
+	return append(out, category)
+}
+
+// unique returns a copy of the supplied strings without duplicates, in the
+// order they first appear.
+func unique(in []string) []string {
+	if in == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
 	}
+	return out
+}
+
+// compositeScope returns the scope of the composite resource defined by the
+// supplied CompositeResourceDefinition, and the spec props Crossplane manages
+// for a composite resource of that scope. Composite resources are cluster
+// scoped unless the CompositeResourceDefinition specifies otherwise. Only cluster
+// scoped composite resources may be claimed.
+func compositeScope(xrd *v1beta1.CompositeResourceDefinition) (extv1.ResourceScope, map[string]extv1.JSONSchemaProps, error) {
+	switch xrd.Spec.Scope {
+	case "", extv1.ClusterScoped:
+		return extv1.ClusterScoped, CompositeResourceSpecProps(), nil
+	case extv1.NamespaceScoped:
+		if xrd.Spec.ClaimNames != nil {
+			return "", nil, errors.New(errNamespacedClaim)
+		}
+		return extv1.NamespaceScoped, NamespacedCompositeResourceSpecProps(), nil
+	}
+	return "", nil, errors.Errorf(errFmtScope, xrd.Spec.Scope)
+}
 
-	s := &extv1.JSONSchemaProps{}
-	if err := json.Unmarshal(v.OpenAPIV3Schema.Raw, s); err != nil {
+// conversion returns the conversion configuration of a CustomResourceDefinition
+// derived from the supplied CompositeResourceDefinition. Versions are not
+// converted unless the CompositeResourceDefinition configures a webhook.
+func conversion(xrd *v1beta1.CompositeResourceDefinition) (*extv1.CustomResourceConversion, error) {
+	c := xrd.Spec.Conversion.DeepCopy()
+	if c == nil || c.Strategy == extv1.NoneConverter {
+		return &extv1.CustomResourceConversion{Strategy: extv1.NoneConverter}, nil
+	}
+	if c.Strategy != extv1.WebhookConverter {
+		return nil, errors.Errorf(errFmtConversionStrategy, c.Strategy)
+	}
+	if c.Service == nil || c.Service.Name == "" {
+		return nil, errors.New(errConversionService)
+	}
+	return &extv1.CustomResourceConversion{
+		Strategy: extv1.WebhookConverter,
+		Webhook: &extv1.WebhookConversion{
+			ClientConfig: &extv1.WebhookClientConfig{
+				Service:  c.Service,
+				CABundle: c.CABundle,
+			},
+			ConversionReviewVersions: []string{"v1", "v1beta1"},
+		},
+	}, nil
+}
+
+// withAgeColumn appends an AGE column to the supplied printer columns, unless
+// they already include one or the supplied CompositeResourceDefinition omits
+// it.
+func withAgeColumn(xrd *v1beta1.CompositeResourceDefinition, cols []extv1.CustomResourceColumnDefinition) []extv1.CustomResourceColumnDefinition {
+	if xrd.Spec.OmitAgeColumn {
+		return cols
+	}
+	for _, c := range cols {
+		if c.Name == "AGE" {
+			return cols
+		}
+	}
+	return append(cols, AgePrinterColumns()...)
+}
+
+// mergePrinterColumns merges the supplied sets of printer columns. A column
+// takes the place of any equivalently named column in an earlier set.
+func mergePrinterColumns(sets ...[]extv1.CustomResourceColumnDefinition) []extv1.CustomResourceColumnDefinition {
+	merged := []extv1.CustomResourceColumnDefinition{}
+	idx := map[string]int{}
+	for _, cols := range sets {
+		for _, c := range cols {
+			if i, ok := idx[c.Name]; ok {
+				merged[i] = c
+				continue
+			}
+			idx[c.Name] = len(merged)
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
+// BuildSchema returns a copy of the supplied base schema with the spec and
+// status properties of the supplied user schema merged into its spec and
+// status. The user schema must be an object at its root; a root with no type is
+// assumed to be one. Properties of the base schema take precedence over those
+// of the user schema. The description of the user schema is also carried over,
+// as is every keyword of its spec and status except additionalProperties, which
+// cannot be combined with the properties Crossplane adds.
+func BuildSchema(base *extv1.JSONSchemaProps, user runtime.RawExtension) (*extv1.JSONSchemaProps, error) {
+	s := base.DeepCopy()
+	if len(user.Raw) == 0 {
+		return s, nil
+	}
+
+	u := &extv1.JSONSchemaProps{}
+	if err := json.Unmarshal(user.Raw, u); err != nil {
 		return nil, errors.Wrap(err, errParseValidation)
 	}
+	if u.Type != "" && u.Type != "object" {
+		return nil, errors.Errorf(errFmtSchemaRootType, u.Type)
+	}
+	if u.Description != "" {
+		s.Description = u.Description
+	}
 
-	spec, ok := s.Properties["spec"]
-	if !ok {
-		return nil, nil
+	if s.Properties == nil {
+		s.Properties = map[string]extv1.JSONSchemaProps{}
+	}
+	spec, err := mergeObject("spec", s.Properties["spec"], u.Properties["spec"])
+	if err != nil {
+		return nil, err
+	}
+	s.Properties["spec"] = spec
+
+	if us, ok := u.Properties["status"]; ok {
+		status, err := mergeObject("status", s.Properties["status"], us)
+		if err != nil {
+			return nil, err
+		}
+		s.Properties["status"] = status
 	}
 
-	return spec.Properties, nil
+	return s, nil
+}
+
+// mergeObject returns a copy of the supplied user object schema with the
+// properties and required properties of the supplied base object schema merged
+// into it. The type and properties of the base schema take precedence, as does
+// its description unless the user schema has one. Every other keyword of the
+// user schema is carried over, except additionalProperties, which cannot be
+// combined with properties and is rejected.
+func mergeObject(name string, base, user extv1.JSONSchemaProps) (extv1.JSONSchemaProps, error) {
+	if user.AdditionalProperties != nil {
+		return extv1.JSONSchemaProps{}, errors.Errorf(errFmtAdditionalProperties, name)
+	}
+
+	out := *user.DeepCopy()
+	if base.Type != "" {
+		out.Type = base.Type
+	}
+	if out.Description == "" {
+		out.Description = base.Description
+	}
+
+	out.Properties = make(map[string]extv1.JSONSchemaProps, len(base.Properties)+len(user.Properties))
+	for k, v := range base.Properties {
+		out.Properties[k] = v
+	}
+	for k, v := range user.Properties {
+		if _, ok := out.Properties[k]; ok {
+			continue
+		}
+		out.Properties[k] = v
+	}
+
+	out.Required = append([]string(nil), base.Required...)
+	for _, r := range user.Required {
+		if !contains(out.Required, r) {
+			out.Required = append(out.Required, r)
+		}
+	}
+
+	return out, nil
+}
+
+// ListEnums returns the enum constrained fields of the referenceable version of
+// the composite resource defined by the supplied CompositeResourceDefinition.
+// Fields are keyed by their path, for example spec.engineVersion, and include
+// any enum constrained fields Crossplane adds per the supplied options.
+func ListEnums(xrd *v1beta1.CompositeResourceDefinition, opts ...Option) (map[string][]extv1.JSON, error) {
+	crd, err := ForCompositeResource(xrd, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	enums := map[string][]extv1.JSON{}
+	for _, vr := range crd.Spec.Versions {
+		if !vr.Storage {
+			continue
+		}
+		walkProps(vr.Schema.OpenAPIV3Schema, func(p *field.Path, s *extv1.JSONSchemaProps) {
+			if len(s.Enum) > 0 {
+				enums[p.String()] = s.Enum
+			}
+		})
+	}
+	return enums, nil
 }
 
 // IsEstablished is a helper function to check whether api-server is ready
@@ -216,3 +626,93 @@ func IsEstablished(s extv1.CustomResourceDefinitionStatus) bool {
 	}
 	return false
 }
+
+// IsEstablishedAndNamesAccepted is a helper function to check whether
+// api-server is ready to accept the instances of registered CRD, and has
+// accepted its names. A CRD may be established while its names conflict with
+// those of another CRD.
+func IsEstablishedAndNamesAccepted(s extv1.CustomResourceDefinitionStatus) bool {
+	established, accepted := false, false
+	for _, c := range s.Conditions {
+		switch c.Type {
+		case extv1.Established:
+			established = c.Status == extv1.ConditionTrue
+		case extv1.NamesAccepted:
+			accepted = c.Status == extv1.ConditionTrue
+		}
+	}
+	return established && accepted
+}
+
+// ServedAPIChanged returns true if the API served for the desired
+// CustomResourceDefinition would differ observably from the API served for the
+// current one. The served API consists of the names, scope, and served versions
+// of a CustomResourceDefinition, and the printer columns of each served
+// version. Changes to the schemas of served versions are not considered.
+func ServedAPIChanged(current, desired *extv1.CustomResourceDefinition) bool {
+	if !equality.Semantic.DeepEqual(current.Spec.Names, desired.Spec.Names) || current.Spec.Scope != desired.Spec.Scope {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(servedColumns(current), servedColumns(desired))
+}
+
+// CanStoreLosslessly returns an error if an object valid under any of the
+// supplied version schemas could lose data when stored as the version at the
+// supplied storage index. This is the case when a version schema has a field
+// that the storage version schema does not. Each error identifies the version
+// by its index, and the field that would be dropped.
+func CanStoreLosslessly(versions []*extv1.JSONSchemaProps, storageIdx int) error {
+	if storageIdx < 0 || storageIdx >= len(versions) {
+		return errors.Errorf(errFmtStorageIndex, storageIdx)
+	}
+	errs := field.ErrorList{}
+	for i, s := range versions {
+		if i == storageIdx || s == nil {
+			continue
+		}
+		errs = append(errs, lossyFields(field.NewPath("versions").Index(i), s, versions[storageIdx])...)
+	}
+	return errs.ToAggregate()
+}
+
+// lossyFields returns an error for each field of the served schema that is not
+// present in the storage schema. Storage schemas that preserve unknown fields
+// store any field.
+func lossyFields(p *field.Path, served, storage *extv1.JSONSchemaProps) field.ErrorList {
+	if storage == nil {
+		return field.ErrorList{field.Forbidden(p, errLossyField)}
+	}
+	if storage.XPreserveUnknownFields != nil && *storage.XPreserveUnknownFields {
+		return nil
+	}
+	errs := field.ErrorList{}
+	for _, k := range sortedKeys(served.Properties) {
+		sp := served.Properties[k]
+		st, ok := storage.Properties[k]
+		if !ok {
+			errs = append(errs, field.Forbidden(p.Child(k), errLossyField))
+			continue
+		}
+		errs = append(errs, lossyFields(p.Child(k), &sp, &st)...)
+	}
+	if served.Items != nil && served.Items.Schema != nil {
+		var st *extv1.JSONSchemaProps
+		if storage.Items != nil {
+			st = storage.Items.Schema
+		}
+		errs = append(errs, lossyFields(p.Key("*"), served.Items.Schema, st)...)
+	}
+	return errs
+}
+
+// servedColumns returns the printer columns of each served version of the
+// supplied CustomResourceDefinition, keyed by version name.
+func servedColumns(crd *extv1.CustomResourceDefinition) map[string][]extv1.CustomResourceColumnDefinition {
+	cols := map[string][]extv1.CustomResourceColumnDefinition{}
+	for _, vr := range crd.Spec.Versions {
+		if vr.Served {
+			cols[vr.Name] = vr.AdditionalPrinterColumns
+		}
+	}
+	return cols
+}
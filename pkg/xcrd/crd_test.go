@@ -23,14 +23,21 @@ limitations under the License.
 package xcrd
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
@@ -68,6 +75,62 @@ func TestIsEstablished(t *testing.T) {
 	}
 }
 
+func TestIsEstablishedAndNamesAccepted(t *testing.T) {
+	cases := map[string]struct {
+		s    extv1.CustomResourceDefinitionStatus
+		want bool
+	}{
+		"EstablishedAndNamesAccepted": {
+			s: extv1.CustomResourceDefinitionStatus{
+				Conditions: []extv1.CustomResourceDefinitionCondition{
+					{Type: extv1.Established, Status: extv1.ConditionTrue},
+					{Type: extv1.NamesAccepted, Status: extv1.ConditionTrue},
+				},
+			},
+			want: true,
+		},
+		"EstablishedNamesNotAccepted": {
+			s: extv1.CustomResourceDefinitionStatus{
+				Conditions: []extv1.CustomResourceDefinitionCondition{
+					{Type: extv1.Established, Status: extv1.ConditionTrue},
+					{Type: extv1.NamesAccepted, Status: extv1.ConditionFalse},
+				},
+			},
+			want: false,
+		},
+		"EstablishedNamesUnknown": {
+			s: extv1.CustomResourceDefinitionStatus{
+				Conditions: []extv1.CustomResourceDefinitionCondition{
+					{Type: extv1.Established, Status: extv1.ConditionTrue},
+				},
+			},
+			want: false,
+		},
+		"NamesAcceptedNotEstablished": {
+			s: extv1.CustomResourceDefinitionStatus{
+				Conditions: []extv1.CustomResourceDefinitionCondition{
+					{Type: extv1.Established, Status: extv1.ConditionFalse},
+					{Type: extv1.NamesAccepted, Status: extv1.ConditionTrue},
+				},
+			},
+			want: false,
+		},
+		"NoConditions": {
+			s:    extv1.CustomResourceDefinitionStatus{},
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := IsEstablishedAndNamesAccepted(tc.s)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("IsEstablishedAndNamesAccepted(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestForCompositeResource(t *testing.T) {
 	name := "coolcomposites.example.org"
 	labels := map[string]string{"cool": "very"}
@@ -127,6 +190,9 @@ func TestForCompositeResource(t *testing.T) {
 				Categories: []string{CategoryComposite},
 			},
 			Scope: extv1.ClusterScoped,
+			Conversion: &extv1.CustomResourceConversion{
+				Strategy: extv1.NoneConverter,
+			},
 			Versions: []extv1.CustomResourceDefinitionVersion{{
 				Name:    version,
 				Served:  true,
@@ -145,6 +211,11 @@ func TestForCompositeResource(t *testing.T) {
 						Type:     "string",
 						JSONPath: ".spec.compositionRef.name",
 					},
+					{
+						Name:     "AGE",
+						Type:     "date",
+						JSONPath: ".metadata.creationTimestamp",
+					},
 				},
 				Schema: &extv1.CustomResourceValidation{
 					OpenAPIV3Schema: &extv1.JSONSchemaProps{
@@ -199,10 +270,22 @@ func TestForCompositeResource(t *testing.T) {
 										Type:     "object",
 										Required: []string{"apiVersion", "kind", "namespace", "name"},
 										Properties: map[string]extv1.JSONSchemaProps{
-											"apiVersion": {Type: "string"},
-											"kind":       {Type: "string"},
-											"namespace":  {Type: "string"},
-											"name":       {Type: "string"},
+											"apiVersion": {
+												Type:        "string",
+												Description: "APIVersion of the claim bound to this composite resource. Managed by Crossplane.",
+											},
+											"kind": {
+												Type:        "string",
+												Description: "Kind of the claim bound to this composite resource. Managed by Crossplane.",
+											},
+											"namespace": {
+												Type:        "string",
+												Description: "Namespace of the claim bound to this composite resource. Managed by Crossplane.",
+											},
+											"name": {
+												Type:        "string",
+												Description: "Name of the claim bound to this composite resource. Managed by Crossplane.",
+											},
 										},
 									},
 									"resourceRefs": {
@@ -277,14 +360,53 @@ func TestForCompositeResource(t *testing.T) {
 	}
 }
 
+func TestValidateName(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		d      *v1beta1.CompositeResourceDefinition
+		want   error
+	}{
+		"ValidName": {
+			reason: "The name of a definition in the form <names.plural>.<group> is valid.",
+			d:      xrd(),
+			want:   nil,
+		},
+		"MismatchedPlural": {
+			reason: "The name of a definition must match its plural name.",
+			d: xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Names.Plural = "coolercomposites"
+			}),
+			want: errors.Errorf(errFmtUnexpectedName, "coolcomposites.example.org", "coolercomposites.example.org"),
+		},
+		"MismatchedGroup": {
+			reason: "The name of a definition must match its group.",
+			d: xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Group = "example.net"
+			}),
+			want: errors.Errorf(errFmtUnexpectedName, "coolcomposites.example.org", "coolcomposites.example.net"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := validateName(tc.d)
+			if diff := cmp.Diff(tc.want, got, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nvalidateName(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestValidateClaimNames(t *testing.T) {
+	p := field.NewPath("spec", "claimNames")
+
 	cases := map[string]struct {
 		d    *v1beta1.CompositeResourceDefinition
-		want error
+		want field.ErrorList
 	}{
 		"MissingClaimNames": {
 			d:    &v1beta1.CompositeResourceDefinition{},
-			want: errors.New(errMissingClaimNames),
+			want: field.ErrorList{field.Required(p, errMissingClaimNames)},
 		},
 		"KindConflict": {
 			d: &v1beta1.CompositeResourceDefinition{
@@ -303,7 +425,26 @@ func TestValidateClaimNames(t *testing.T) {
 					},
 				},
 			},
-			want: errors.Errorf(errFmtConflictingClaimName, "a"),
+			want: field.ErrorList{field.Invalid(p.Child("kind"), "a", fmt.Sprintf(errFmtConflictingClaimName, "a"))},
+		},
+		"KindCaseConflict": {
+			d: &v1beta1.CompositeResourceDefinition{
+				Spec: v1beta1.CompositeResourceDefinitionSpec{
+					ClaimNames: &extv1.CustomResourceDefinitionNames{
+						Kind:     "database",
+						ListKind: "a",
+						Singular: "a",
+						Plural:   "a",
+					},
+					Names: extv1.CustomResourceDefinitionNames{
+						Kind:     "Database",
+						ListKind: "b",
+						Singular: "b",
+						Plural:   "b",
+					},
+				},
+			},
+			want: field.ErrorList{field.Invalid(p.Child("kind"), "database", fmt.Sprintf(errFmtConflictingClaimKind, "database", "Database"))},
 		},
 		"ListKindConflict": {
 			d: &v1beta1.CompositeResourceDefinition{
@@ -322,7 +463,7 @@ func TestValidateClaimNames(t *testing.T) {
 					},
 				},
 			},
-			want: errors.Errorf(errFmtConflictingClaimName, "a"),
+			want: field.ErrorList{field.Invalid(p.Child("listKind"), "a", fmt.Sprintf(errFmtConflictingClaimName, "a"))},
 		},
 		"SingularConflict": {
 			d: &v1beta1.CompositeResourceDefinition{
@@ -341,7 +482,7 @@ func TestValidateClaimNames(t *testing.T) {
 					},
 				},
 			},
-			want: errors.Errorf(errFmtConflictingClaimName, "a"),
+			want: field.ErrorList{field.Invalid(p.Child("singular"), "a", fmt.Sprintf(errFmtConflictingClaimName, "a"))},
 		},
 		"PluralConflict": {
 			d: &v1beta1.CompositeResourceDefinition{
@@ -361,7 +502,52 @@ func TestValidateClaimNames(t *testing.T) {
 					},
 				},
 			},
-			want: errors.Errorf(errFmtConflictingClaimName, "a"),
+			want: field.ErrorList{field.Invalid(p.Child("plural"), "a", fmt.Sprintf(errFmtConflictingClaimName, "a"))},
+		},
+		"ShortNameConflict": {
+			d: &v1beta1.CompositeResourceDefinition{
+				Spec: v1beta1.CompositeResourceDefinitionSpec{
+					ClaimNames: &extv1.CustomResourceDefinitionNames{
+						Kind:       "a",
+						ListKind:   "a",
+						Singular:   "a",
+						Plural:     "a",
+						ShortNames: []string{"x", "db"},
+					},
+					Names: extv1.CustomResourceDefinitionNames{
+						Kind:       "b",
+						ListKind:   "b",
+						Singular:   "b",
+						Plural:     "b",
+						ShortNames: []string{"db"},
+					},
+				},
+			},
+			want: field.ErrorList{field.Invalid(p.Child("shortNames").Index(1), "db", fmt.Sprintf(errFmtConflictingClaimName, "db"))},
+		},
+		"MultipleConflicts": {
+			d: &v1beta1.CompositeResourceDefinition{
+				Spec: v1beta1.CompositeResourceDefinitionSpec{
+					ClaimNames: &extv1.CustomResourceDefinitionNames{
+						Kind:     "a",
+						ListKind: "b",
+						Singular: "c",
+						Plural:   "d",
+					},
+					Names: extv1.CustomResourceDefinitionNames{
+						Kind:     "a",
+						ListKind: "b",
+						Singular: "c",
+						Plural:   "d",
+					},
+				},
+			},
+			want: field.ErrorList{
+				field.Invalid(p.Child("kind"), "a", fmt.Sprintf(errFmtConflictingClaimName, "a")),
+				field.Invalid(p.Child("listKind"), "b", fmt.Sprintf(errFmtConflictingClaimName, "b")),
+				field.Invalid(p.Child("singular"), "c", fmt.Sprintf(errFmtConflictingClaimName, "c")),
+				field.Invalid(p.Child("plural"), "d", fmt.Sprintf(errFmtConflictingClaimName, "d")),
+			},
 		},
 	}
 
@@ -446,6 +632,9 @@ func TestForCompositeResourceClaim(t *testing.T) {
 				Categories: []string{CategoryClaim},
 			},
 			Scope: extv1.NamespaceScoped,
+			Conversion: &extv1.CustomResourceConversion{
+				Strategy: extv1.NoneConverter,
+			},
 			Versions: []extv1.CustomResourceDefinitionVersion{
 				{
 					Name:    version,
@@ -465,6 +654,11 @@ func TestForCompositeResourceClaim(t *testing.T) {
 							Type:     "string",
 							JSONPath: ".spec.writeConnectionSecretToRef.name",
 						},
+						{
+							Name:     "AGE",
+							Type:     "date",
+							JSONPath: ".metadata.creationTimestamp",
+						},
 					},
 					Schema: &extv1.CustomResourceValidation{
 						OpenAPIV3Schema: &extv1.JSONSchemaProps{
@@ -580,3 +774,3009 @@ func TestForCompositeResourceClaim(t *testing.T) {
 		t.Errorf("ForCompositeResourceClaim(...): -want, +got:\n%s", diff)
 	}
 }
+
+type xrdModifier func(d *v1beta1.CompositeResourceDefinition)
+
+func withSchema(schema string) xrdModifier {
+	return func(d *v1beta1.CompositeResourceDefinition) {
+		for i := range d.Spec.Versions {
+			d.Spec.Versions[i].Schema = &v1beta1.CompositeResourceValidation{
+				OpenAPIV3Schema: runtime.RawExtension{Raw: []byte(schema)},
+			}
+		}
+	}
+}
+
+func xrd(m ...xrdModifier) *v1beta1.CompositeResourceDefinition {
+	d := &v1beta1.CompositeResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "coolcomposites.example.org",
+			UID:  types.UID("you-you-eye-dee"),
+		},
+		Spec: v1beta1.CompositeResourceDefinitionSpec{
+			Group: "example.org",
+			Names: extv1.CustomResourceDefinitionNames{
+				Plural:   "coolcomposites",
+				Singular: "coolcomposite",
+				Kind:     "CoolComposite",
+				ListKind: "CoolCompositeList",
+			},
+			ClaimNames: &extv1.CustomResourceDefinitionNames{
+				Plural:   "coolclaims",
+				Singular: "coolclaim",
+				Kind:     "CoolClaim",
+				ListKind: "CoolClaimList",
+			},
+			Versions: []v1beta1.CompositeResourceDefinitionVersion{{
+				Name:          "v1beta1",
+				Referenceable: true,
+				Served:        true,
+			}},
+		},
+	}
+
+	for _, fn := range m {
+		fn(d)
+	}
+
+	return d
+}
+
+func TestOptionalSpecProps(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		prop   string
+		want   *extv1.JSONSchemaProps
+	}{
+		"ManagementPoliciesDisabled": {
+			reason: "The managementPolicies field should not be present by default.",
+			prop:   "managementPolicies",
+			want:   nil,
+		},
+		"ManagementPoliciesEnabled": {
+			reason: "The managementPolicies field should be an array of the supported policies.",
+			opts:   []Option{WithManagementPolicies()},
+			prop:   "managementPolicies",
+			want: &extv1.JSONSchemaProps{
+				Type: "array",
+				Items: &extv1.JSONSchemaPropsOrArray{
+					Schema: &extv1.JSONSchemaProps{
+						Type: "string",
+						Enum: []extv1.JSON{
+							{Raw: []byte(`"Observe"`)},
+							{Raw: []byte(`"Create"`)},
+							{Raw: []byte(`"Update"`)},
+							{Raw: []byte(`"Delete"`)},
+							{Raw: []byte(`"LateInitialize"`)},
+							{Raw: []byte(`"*"`)},
+						},
+					},
+				},
+			},
+		},
+		"ManagementPoliciesObserveOnly": {
+			reason: "The managementPolicies field should be constrained to exactly Observe.",
+			opts:   []Option{WithObserveOnlyManagementPolicies()},
+			prop:   "managementPolicies",
+			want: &extv1.JSONSchemaProps{
+				Type:     "array",
+				MinItems: func() *int64 { i := int64(1); return &i }(),
+				MaxItems: func() *int64 { i := int64(1); return &i }(),
+				Default:  &extv1.JSON{Raw: []byte(`["Observe"]`)},
+				Items: &extv1.JSONSchemaPropsOrArray{
+					Schema: &extv1.JSONSchemaProps{
+						Type: "string",
+						Enum: []extv1.JSON{{Raw: []byte(`"Observe"`)}},
+					},
+				},
+			},
+		},
+		"DeletionPolicyDisabled": {
+			reason: "The deletionPolicy field should not be present by default.",
+			prop:   "deletionPolicy",
+			want:   nil,
+		},
+		"DeletionPolicyEnforced": {
+			reason: "The deletionPolicy field should default to Delete, and reject Orphan.",
+			opts:   []Option{WithEnforcedDeletePolicy()},
+			prop:   "deletionPolicy",
+			want: &extv1.JSONSchemaProps{
+				Description: "DeletionPolicy specifies what will happen to composed resources when this resource is deleted. Only Delete is allowed.",
+				Type:        "string",
+				Default:     &extv1.JSON{Raw: []byte(`"Delete"`)},
+				Enum:        []extv1.JSON{{Raw: []byte(`"Delete"`)}},
+			},
+		},
+		"EnvironmentDisabled": {
+			reason: "The environment field should not be present by default.",
+			prop:   "environment",
+			want:   nil,
+		},
+		"EnvironmentEnabled": {
+			reason: "The environment field should allow EnvironmentConfigs to be referenced or selected.",
+			opts:   []Option{WithEnvironmentField()},
+			prop:   "environment",
+			want: &extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"environmentConfigs": {
+						Type: "array",
+						Items: &extv1.JSONSchemaPropsOrArray{
+							Schema: &extv1.JSONSchemaProps{
+								Type: "object",
+								Properties: map[string]extv1.JSONSchemaProps{
+									"ref": {
+										Type:     "object",
+										Required: []string{"name"},
+										Properties: map[string]extv1.JSONSchemaProps{
+											"name": {Type: "string"},
+										},
+									},
+									"selector": {
+										Type:     "object",
+										Required: []string{"matchLabels"},
+										Properties: map[string]extv1.JSONSchemaProps{
+											"matchLabels": {
+												Type: "object",
+												AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+													Allows: true,
+													Schema: &extv1.JSONSchemaProps{Type: "string"},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(), tc.opts...)
+			if err != nil {
+				t.Fatalf("ForCompositeResource(...): %s", err)
+			}
+			var got *extv1.JSONSchemaProps
+			if p, ok := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties[tc.prop]; ok {
+				got = &p
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithManagedFieldsNested(t *testing.T) {
+	type want struct {
+		spec    extv1.JSONSchemaProps
+		columns []extv1.CustomResourceColumnDefinition
+	}
+
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		want   want
+	}{
+		"Composite": {
+			reason: "Composite resource managed fields and printer columns should be nested under spec.crossplane.",
+			render: ForCompositeResource,
+			want: want{
+				spec: extv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"crossplane": {Type: "object", Properties: CompositeResourceSpecProps()},
+					},
+				},
+				columns: []extv1.CustomResourceColumnDefinition{
+					{
+						Name:     "READY",
+						Type:     "string",
+						JSONPath: ".status.conditions[?(@.type=='Ready')].status",
+					},
+					{
+						Name:     "COMPOSITION",
+						Type:     "string",
+						JSONPath: ".spec.crossplane.compositionRef.name",
+					},
+					AgePrinterColumns()[0],
+				},
+			},
+		},
+		"Claim": {
+			reason: "Composite resource claim managed fields and printer columns should be nested under spec.crossplane.",
+			render: ForCompositeResourceClaim,
+			want: want{
+				spec: extv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"crossplane": {Type: "object", Properties: CompositeResourceClaimSpecProps()},
+					},
+				},
+				columns: []extv1.CustomResourceColumnDefinition{
+					{
+						Name:     "READY",
+						Type:     "string",
+						JSONPath: ".status.conditions[?(@.type=='Ready')].status",
+					},
+					{
+						Name:     "CONNECTION-SECRET",
+						Type:     "string",
+						JSONPath: ".spec.crossplane.writeConnectionSecretToRef.name",
+					},
+					AgePrinterColumns()[0],
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(xrd(), WithManagedFieldsNested())
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.spec, crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want spec, +got spec:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.columns, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want columns, +got columns:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithPhaseField(t *testing.T) {
+	type want struct {
+		phase   extv1.JSONSchemaProps
+		columns []extv1.CustomResourceColumnDefinition
+	}
+
+	columns := append(CompositeResourcePrinterColumns(), extv1.CustomResourceColumnDefinition{
+		Name:     "PHASE",
+		Type:     "string",
+		JSONPath: ".status.phase",
+	}, AgePrinterColumns()[0])
+
+	cases := map[string]struct {
+		reason string
+		phases []string
+		want   want
+	}{
+		"Unconstrained": {
+			reason: "The phase field should be a free string when no phases are supplied.",
+			want: want{
+				phase:   extv1.JSONSchemaProps{Type: "string"},
+				columns: columns,
+			},
+		},
+		"Constrained": {
+			reason: "The phase field should be constrained to the supplied phases.",
+			phases: []string{"Pending", "Ready"},
+			want: want{
+				phase: extv1.JSONSchemaProps{
+					Type: "string",
+					Enum: []extv1.JSON{
+						{Raw: []byte(`"Pending"`)},
+						{Raw: []byte(`"Ready"`)},
+					},
+				},
+				columns: columns,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(), WithPhaseField(tc.phases))
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.phase, crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"].Properties["phase"]); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want phase, +got phase:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.columns, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want columns, +got columns:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithForProviderPassthrough(t *testing.T) {
+	preserve := true
+
+	cases := map[string]struct {
+		reason string
+		d      *v1beta1.CompositeResourceDefinition
+		want   extv1.JSONSchemaProps
+	}{
+		"NotDefined": {
+			reason: "A forProvider object that preserves unknown fields should be added if the schema does not define one.",
+			d:      xrd(),
+			want:   extv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: &preserve},
+		},
+		"Defined": {
+			reason: "A forProvider object defined by the schema should preserve unknown fields.",
+			d:      xrd(withSchema(`{"properties":{"spec":{"properties":{"forProvider":{"type":"object","properties":{"region":{"type":"string"}}}}}}}`)),
+			want: extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"region": {Type: "string"},
+				},
+				XPreserveUnknownFields: &preserve,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(tc.d, WithForProviderPassthrough())
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+			if diff := cmp.Diff(tc.want, spec.Properties["forProvider"]); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if spec.XPreserveUnknownFields != nil {
+				t.Errorf("\n%s\nForCompositeResource(...): spec should not preserve unknown fields", tc.reason)
+			}
+		})
+	}
+}
+
+func TestWithInitProviderPassthrough(t *testing.T) {
+	preserve := true
+
+	cases := map[string]struct {
+		reason string
+		d      *v1beta1.CompositeResourceDefinition
+		want   extv1.JSONSchemaProps
+	}{
+		"NotDefined": {
+			reason: "An initProvider object that preserves unknown fields should be added if the schema does not define one.",
+			d:      xrd(),
+			want:   extv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: &preserve},
+		},
+		"Defined": {
+			reason: "An initProvider object defined by the schema should preserve unknown fields.",
+			d:      xrd(withSchema(`{"properties":{"spec":{"properties":{"initProvider":{"type":"object","properties":{"zone":{"type":"string"}}}}}}}`)),
+			want: extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"zone": {Type: "string"},
+				},
+				XPreserveUnknownFields: &preserve,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(tc.d, WithInitProviderPassthrough())
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+			if diff := cmp.Diff(tc.want, spec.Properties["initProvider"]); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if _, ok := spec.Properties["forProvider"]; ok {
+				t.Errorf("\n%s\nForCompositeResource(...): spec.forProvider should not be added", tc.reason)
+			}
+		})
+	}
+}
+
+func TestWithAtProviderStatusPassthrough(t *testing.T) {
+	preserve := true
+
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+	}{
+		"Composite": {
+			reason: "The composite resource CRD should have a status.atProvider object that preserves unknown fields.",
+			render: ForCompositeResource,
+		},
+		"Claim": {
+			reason: "The composite resource claim CRD should have a status.atProvider object that preserves unknown fields.",
+			render: ForCompositeResourceClaim,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(xrd(), WithAtProviderStatusPassthrough())
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			status := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"]
+			want := extv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: &preserve}
+			if diff := cmp.Diff(want, status.Properties["atProvider"]); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want atProvider, +got atProvider:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(CompositeResourceStatusProps()["conditions"], status.Properties["conditions"]); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want conditions, +got conditions:\n%s", tc.reason, diff)
+			}
+			if status.XPreserveUnknownFields != nil {
+				t.Errorf("\n%s\nrender(...): status should not preserve unknown fields", tc.reason)
+			}
+		})
+	}
+}
+
+func TestWithXRDUIDAnnotation(t *testing.T) {
+	d := xrd(func(d *v1beta1.CompositeResourceDefinition) {
+		d.SetAnnotations(map[string]string{"example.org/cool": "very"})
+	})
+
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+	}{
+		"Composite": {
+			reason: "The composite resource CRD should be annotated with the UID of its XRD.",
+			render: ForCompositeResource,
+		},
+		"Claim": {
+			reason: "The composite resource claim CRD should be annotated with the UID of its XRD.",
+			render: ForCompositeResourceClaim,
+		},
+	}
+
+	want := map[string]string{
+		"example.org/cool":  "very",
+		AnnotationKeyXRDUID: string(d.GetUID()),
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(d, WithXRDUIDAnnotation())
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(want, crd.GetAnnotations()); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if _, ok := d.GetAnnotations()[AnnotationKeyXRDUID]; ok {
+				t.Errorf("\n%s\nrender(...): must not annotate the XRD", tc.reason)
+			}
+		})
+	}
+}
+
+func TestWithoutExtensions(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{
+		"config":{"type":"object","x-kubernetes-preserve-unknown-fields":true},
+		"port":{"x-kubernetes-int-or-string":true},
+		"zones":{"type":"array","x-kubernetes-list-type":"map","x-kubernetes-list-map-keys":["name"],"items":{"type":"object","x-kubernetes-map-type":"atomic","properties":{"name":{"type":"string"}}}}
+	}}}}`
+
+	want := map[string]extv1.JSONSchemaProps{
+		"config": {Type: "object"},
+		"port":   {},
+		"zones": {
+			Type: "array",
+			Items: &extv1.JSONSchemaPropsOrArray{
+				Schema: &extv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"name": {Type: "string"},
+					},
+				},
+			},
+		},
+	}
+
+	var warnings []string
+	crd, err := ForCompositeResource(xrd(withSchema(schema)), WithoutExtensions(), WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	}))
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	props := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties
+	for k, w := range want {
+		if diff := cmp.Diff(w, props[k]); diff != "" {
+			t.Errorf("ForCompositeResource(...): spec.%s: -want, +got:\n%s", k, diff)
+		}
+	}
+	if diff := cmp.Diff([]string{fmt.Sprintf(warnFmtStrippedExtensions, "v1beta1")}, warnings); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want warnings, +got warnings:\n%s", diff)
+	}
+}
+
+func TestWithXRDLabel(t *testing.T) {
+	d := xrd(func(d *v1beta1.CompositeResourceDefinition) {
+		d.SetLabels(map[string]string{"cool": "very"})
+	})
+
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+	}{
+		"Composite": {
+			reason: "The composite resource CRD should be labelled with the name of its XRD.",
+			render: ForCompositeResource,
+		},
+		"Claim": {
+			reason: "The composite resource claim CRD should be labelled with the name of its XRD.",
+			render: ForCompositeResourceClaim,
+		},
+	}
+
+	want := map[string]string{
+		"cool":      "very",
+		LabelKeyXRD: d.GetName(),
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(d, WithXRDLabel())
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(want, crd.GetLabels()); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithPropagatedMetadata(t *testing.T) {
+	d := xrd(func(d *v1beta1.CompositeResourceDefinition) {
+		d.SetLabels(map[string]string{"cool": "very", "internal.example.org/owner": "ops"})
+		d.SetAnnotations(map[string]string{"cool": "very", "internal.example.org/ticket": "123"})
+	})
+
+	type want struct {
+		labels      map[string]string
+		annotations map[string]string
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		want   want
+	}{
+		"Default": {
+			reason: "All labels and annotations should be propagated by default.",
+			want: want{
+				labels:      map[string]string{"cool": "very", "internal.example.org/owner": "ops"},
+				annotations: map[string]string{"cool": "very", "internal.example.org/ticket": "123"},
+			},
+		},
+		"AllowList": {
+			reason: "Only allowed labels and annotations should be propagated.",
+			opts:   []Option{WithPropagatedLabels("cool"), WithPropagatedAnnotations("cool", "missing")},
+			want: want{
+				labels:      map[string]string{"cool": "very"},
+				annotations: map[string]string{"cool": "very"},
+			},
+		},
+		"None": {
+			reason: "No labels or annotations should be propagated given an empty allow-list.",
+			opts:   []Option{WithPropagatedLabels(), WithPropagatedAnnotations()},
+		},
+		"AllowListWithXRDLabel": {
+			reason: "The XRD label should be added alongside any allowed labels.",
+			opts:   []Option{WithPropagatedLabels("cool"), WithXRDLabel()},
+			want: want{
+				labels:      map[string]string{"cool": "very", LabelKeyXRD: d.GetName()},
+				annotations: map[string]string{"cool": "very", "internal.example.org/ticket": "123"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(d, tc.opts...)
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.labels, crd.GetLabels()); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want labels, +got labels:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.annotations, crd.GetAnnotations()); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want annotations, +got annotations:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithLenientConditions(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		want   []string
+	}{
+		"Default": {
+			reason: "Conditions should require a reason by default.",
+			want:   []string{"lastTransitionTime", "reason", "status", "type"},
+		},
+		"Lenient": {
+			reason: "Conditions should not require a reason when lenient.",
+			opts:   []Option{WithLenientConditions()},
+			want:   []string{"lastTransitionTime", "status", "type"},
+		},
+		"Strict": {
+			reason: "Conditions should require a message when strict, per metav1.Condition.",
+			opts:   []Option{WithStrictConditions()},
+			want:   []string{"lastTransitionTime", "message", "reason", "status", "type"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(), tc.opts...)
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			c := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"].Properties["conditions"]
+			if diff := cmp.Diff(tc.want, c.Items.Schema.Required); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithSSAFriendlyLists(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"zones":{"type":"array","x-kubernetes-list-type":"set","items":{"type":"string"}},"tags":{"type":"array","items":{"type":"string"}}}}}}`
+	d := xrd(withSchema(schema))
+
+	crd, err := ForCompositeResource(d, WithSSAFriendlyLists(), WithManagementPolicies(), WithStatusResourceRefs())
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+	nested, err := ForCompositeResource(d, WithSSAFriendlyLists(), WithManagementPolicies(), WithManagedFieldsNested())
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	listType := func(s string) *string { return &s }
+	props := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties
+	nprops := nested.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties
+	cases := map[string]struct {
+		reason string
+		prop   extv1.JSONSchemaProps
+		want   *string
+	}{
+		"SpecResourceRefs": {
+			reason: "Resource references should be an atomic list.",
+			prop:   props["spec"].Properties["resourceRefs"],
+			want:   listType("atomic"),
+		},
+		"SpecManagementPolicies": {
+			reason: "Management policies should be a set.",
+			prop:   props["spec"].Properties["managementPolicies"],
+			want:   listType("set"),
+		},
+		"NestedSpecResourceRefs": {
+			reason: "Resource references should be an atomic list when managed fields are nested.",
+			prop:   nprops["spec"].Properties["crossplane"].Properties["resourceRefs"],
+			want:   listType("atomic"),
+		},
+		"NestedSpecManagementPolicies": {
+			reason: "Management policies are not managed fields, and should be a set when managed fields are nested.",
+			prop:   nprops["spec"].Properties["managementPolicies"],
+			want:   listType("set"),
+		},
+		"StatusResourceRefs": {
+			reason: "Observed resource references should be an atomic list.",
+			prop:   props["status"].Properties["resourceRefs"],
+			want:   listType("atomic"),
+		},
+		"StatusConditions": {
+			reason: "Conditions should be a map list.",
+			prop:   props["status"].Properties["conditions"],
+			want:   listType("map"),
+		},
+		"UserListType": {
+			reason: "List types set by the schema of the XRD should be carried through.",
+			prop:   props["spec"].Properties["zones"],
+			want:   listType("set"),
+		},
+		"UserWithoutListType": {
+			reason: "Arrays of the XRD schema without a list type should be unchanged.",
+			prop:   props["spec"].Properties["tags"],
+			want:   nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.prop.XListType); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want list type, +got list type:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithConditionStatusEnum(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		want   extv1.JSONSchemaProps
+	}{
+		"Default": {
+			reason: "Condition status should be a free string by default.",
+			want:   extv1.JSONSchemaProps{Type: "string"},
+		},
+		"Enum": {
+			reason: "Condition status should be constrained to True, False, or Unknown when enabled.",
+			opts:   []Option{WithConditionStatusEnum()},
+			want: extv1.JSONSchemaProps{
+				Type: "string",
+				Enum: []extv1.JSON{
+					{Raw: []byte(`"True"`)},
+					{Raw: []byte(`"False"`)},
+					{Raw: []byte(`"Unknown"`)},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(), tc.opts...)
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			c := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"].Properties["conditions"]
+			if diff := cmp.Diff(tc.want, c.Items.Schema.Properties["status"]); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithConditionsMapList(t *testing.T) {
+	listType := "map"
+
+	cases := map[string]struct {
+		reason   string
+		render   func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		opts     []Option
+		listType *string
+		mapKeys  []string
+	}{
+		"Default": {
+			reason: "Conditions should be an atomic list by default.",
+			render: ForCompositeResource,
+		},
+		"Composite": {
+			reason:   "Composite resource conditions should be a map list keyed by type when enabled.",
+			render:   ForCompositeResource,
+			opts:     []Option{WithConditionsMapList()},
+			listType: &listType,
+			mapKeys:  []string{"type"},
+		},
+		"Claim": {
+			reason:   "Composite resource claim conditions should be a map list keyed by type when enabled.",
+			render:   ForCompositeResourceClaim,
+			opts:     []Option{WithConditionsMapList()},
+			listType: &listType,
+			mapKeys:  []string{"type"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(xrd(), tc.opts...)
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			c := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"].Properties["conditions"]
+			if diff := cmp.Diff(tc.listType, c.XListType); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want list type, +got list type:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.mapKeys, c.XListMapKeys); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want map keys, +got map keys:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithVersionFlagsFromAnnotations(t *testing.T) {
+	withAnnotations := func(a map[string]string) xrdModifier {
+		return func(d *v1beta1.CompositeResourceDefinition) {
+			d.SetAnnotations(a)
+			d.Spec.Versions = append(d.Spec.Versions, v1beta1.CompositeResourceDefinitionVersion{Name: "v1"})
+		}
+	}
+
+	type flags struct {
+		Served  bool
+		Storage bool
+	}
+
+	type want struct {
+		flags map[string]flags
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		d      *v1beta1.CompositeResourceDefinition
+		want   want
+	}{
+		"NoAnnotations": {
+			reason: "Versions should be served and stored per their own flags if there are no annotations.",
+			d:      xrd(withAnnotations(nil)),
+			want: want{
+				flags: map[string]flags{
+					"v1beta1": {Served: true, Storage: true},
+					"v1":      {},
+				},
+			},
+		},
+		"Annotations": {
+			reason: "Versions should be served and stored per the annotations, if any.",
+			d: xrd(withAnnotations(map[string]string{
+				AnnotationKeyServedVersions: "v1beta1, v1",
+				AnnotationKeyStorageVersion: "v1",
+			})),
+			want: want{
+				flags: map[string]flags{
+					"v1beta1": {Served: true},
+					"v1":      {Served: true, Storage: true},
+				},
+			},
+		},
+		"UnknownServedVersion": {
+			reason: "An error should be returned if the served annotation references an unknown version.",
+			d:      xrd(withAnnotations(map[string]string{AnnotationKeyServedVersions: "v1,v2"})),
+			want: want{
+				err: errors.Errorf(errFmtUnknownAnnotatedVersion, AnnotationKeyServedVersions, "v2"),
+			},
+		},
+		"UnknownStorageVersion": {
+			reason: "An error should be returned if the storage annotation references an unknown version.",
+			d:      xrd(withAnnotations(map[string]string{AnnotationKeyStorageVersion: "v2"})),
+			want: want{
+				err: errors.Errorf(errFmtUnknownAnnotatedVersion, AnnotationKeyStorageVersion, "v2"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(tc.d, WithVersionFlagsFromAnnotations())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nForCompositeResource(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			got := map[string]flags{}
+			for _, vr := range crd.Spec.Versions {
+				got[vr.Name] = flags{Served: vr.Served, Storage: vr.Storage}
+			}
+			if diff := cmp.Diff(tc.want.flags, got); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithMaxDescriptionLength(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"a":{"type":"object","description":"A rather long description.","properties":{"b":{"type":"string","description":"Another long description."}}}}}}}`
+
+	crd, err := ForCompositeResource(xrd(withSchema(schema)), WithMaxDescriptionLength(10, true))
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	want := extv1.JSONSchemaProps{
+		Type:        "object",
+		Description: "A rather l",
+		Properties: map[string]extv1.JSONSchemaProps{
+			"b": {Type: "string", Description: "Another lo"},
+		},
+	}
+	if diff := cmp.Diff(want, crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["a"]); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want, +got:\n%s", diff)
+	}
+
+	long := xrd(withSchema(`{"properties":{"spec":{"properties":{"a":{"type":"string"}}}}}`), func(d *v1beta1.CompositeResourceDefinition) {
+		d.Spec.Description = "A rather long description."
+	})
+	_, err = ForCompositeResource(long, WithMaxDescriptionLength(10, false))
+	wantErr := errors.Wrapf(field.ErrorList{
+		field.TooLong(field.NewPath("description"), "A rather long description.", 10),
+	}.ToAggregate(), errFmtInvalidVersion, "v1beta1")
+	if diff := cmp.Diff(wantErr, err, test.EquateErrors()); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want error, +got error:\n%s", diff)
+	}
+}
+
+func TestCustomPrinterColumns(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"replicas":{"type":"integer"}}}}}`
+	custom := extv1.CustomResourceColumnDefinition{
+		Name:        "REPLICAS",
+		Type:        "integer",
+		Format:      "int32",
+		Description: "The number of replicas.",
+		Priority:    1,
+		JSONPath:    ".spec.replicas",
+	}
+
+	d := xrd(withSchema(schema), func(d *v1beta1.CompositeResourceDefinition) {
+		d.Spec.Versions[0].AdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{custom}
+	})
+
+	crd, err := ForCompositeResource(d, WithStrictValidation())
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	want := append(CompositeResourcePrinterColumns(), custom, AgePrinterColumns()[0])
+	if diff := cmp.Diff(want, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestWithTagsField(t *testing.T) {
+	_, errRegex := regexp.Compile("[a-z")
+
+	type want struct {
+		tags extv1.JSONSchemaProps
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason       string
+		keyPattern   string
+		valuePattern string
+		want         want
+	}{
+		"ValidPatterns": {
+			reason:       "A spec.tags object whose values must match the value pattern should be added.",
+			keyPattern:   "^[a-z]+$",
+			valuePattern: "^[a-z0-9]*$",
+			want: want{
+				tags: extv1.JSONSchemaProps{
+					Description: "Tags to apply to the resource. Tag keys must match the pattern ^[a-z]+$.",
+					Type:        "object",
+					AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+						Allows: true,
+						Schema: &extv1.JSONSchemaProps{Type: "string", Pattern: "^[a-z0-9]*$"},
+					},
+				},
+			},
+		},
+		"InvalidKeyPattern": {
+			reason:     "An error should be returned if the key pattern is not a valid regular expression.",
+			keyPattern: "[a-z",
+			want: want{
+				err: errors.Wrapf(errRegex, errFmtInvalidTagsRegex, "key"),
+			},
+		},
+		"InvalidValuePattern": {
+			reason:       "An error should be returned if the value pattern is not a valid regular expression.",
+			valuePattern: "[a-z",
+			want: want{
+				err: errors.Wrapf(errRegex, errFmtInvalidTagsRegex, "value"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(), WithTagsField(tc.keyPattern, tc.valuePattern))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nForCompositeResource(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.tags, crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["tags"]); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestForCompositeResourceShell(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"size":{"type":"string"}}},"status":{"properties":{"address":{"type":"string"}}}}}`
+	d := xrd(withSchema(schema), func(d *v1beta1.CompositeResourceDefinition) {
+		d.Spec.Versions[0].AdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{
+			{Name: "SIZE", Type: "string", JSONPath: ".spec.size"},
+		}
+	})
+
+	got, err := ForCompositeResourceShell(d)
+	if err != nil {
+		t.Fatalf("ForCompositeResourceShell(...): %s", err)
+	}
+
+	want, err := ForCompositeResource(xrd())
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ForCompositeResourceShell(...): -want, +got:\n%s", diff)
+	}
+	if _, ok := got.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["compositionRef"]; !ok {
+		t.Errorf("ForCompositeResourceShell(...): spec.compositionRef should be present")
+	}
+	if _, ok := got.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["size"]; ok {
+		t.Errorf("ForCompositeResourceShell(...): spec.size should not be present")
+	}
+	if d.Spec.Versions[0].Schema == nil {
+		t.Errorf("ForCompositeResourceShell(...): must not mutate the supplied CompositeResourceDefinition")
+	}
+}
+
+func TestMergedPrinterColumns(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"size":{"type":"string"},"region":{"type":"string"},"zone":{"type":"string"}}}}}`
+	size := extv1.CustomResourceColumnDefinition{Name: "SIZE", Type: "string", JSONPath: ".spec.size"}
+	wideSize := extv1.CustomResourceColumnDefinition{Name: "SIZE", Type: "string", JSONPath: ".spec.size", Priority: 1, Description: "The size."}
+	region := extv1.CustomResourceColumnDefinition{Name: "REGION", Type: "string", JSONPath: ".spec.region"}
+	zone := extv1.CustomResourceColumnDefinition{Name: "ZONE", Type: "string", JSONPath: ".spec.zone"}
+
+	d := xrd(func(d *v1beta1.CompositeResourceDefinition) {
+		d.Spec.AdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{size, region}
+		d.Spec.Versions = []v1beta1.CompositeResourceDefinitionVersion{
+			{
+				Name:                     "v1beta1",
+				Served:                   true,
+				AdditionalPrinterColumns: []extv1.CustomResourceColumnDefinition{wideSize},
+			},
+			{
+				Name:                     "v1",
+				Served:                   true,
+				Referenceable:            true,
+				AdditionalPrinterColumns: []extv1.CustomResourceColumnDefinition{zone},
+			},
+		}
+	}, withSchema(schema))
+
+	crd, err := ForCompositeResource(d)
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	want := map[string][]extv1.CustomResourceColumnDefinition{
+		"v1beta1": append(CompositeResourcePrinterColumns(), wideSize, region, AgePrinterColumns()[0]),
+		"v1":      append(CompositeResourcePrinterColumns(), size, region, zone, AgePrinterColumns()[0]),
+	}
+	got := map[string][]extv1.CustomResourceColumnDefinition{}
+	for _, vr := range crd.Spec.Versions {
+		got[vr.Name] = vr.AdditionalPrinterColumns
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestCompositionRefNamespace(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+	}{
+		"Composite": {
+			reason: "A composite resource's spec.compositionRef should not have a namespace.",
+			render: ForCompositeResource,
+		},
+		"Claim": {
+			reason: "A composite resource claim's spec.compositionRef should not have a namespace.",
+			render: ForCompositeResourceClaim,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(xrd())
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			if _, ok := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["compositionRef"].Properties["namespace"]; ok {
+				t.Errorf("\n%s\nrender(...): spec.compositionRef should not have a namespace", tc.reason)
+			}
+		})
+	}
+}
+
+func TestReservedFields(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		opts   []Option
+		m      []xrdModifier
+		schema string
+		want   error
+	}{
+		"CompositeResourceRefs": {
+			reason: "A composite resource schema that defines spec.resourceRefs should be rejected.",
+			render: ForCompositeResource,
+			schema: `{"properties":{"spec":{"properties":{"resourceRefs":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "resourceRefs"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"CompositeCompositionRef": {
+			reason: "A composite resource schema that defines spec.compositionRef should be rejected.",
+			render: ForCompositeResource,
+			schema: `{"properties":{"spec":{"properties":{"compositionRef":{"type":"object","properties":{"namespace":{"type":"string"}}}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "compositionRef"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"ClaimResourceRef": {
+			reason: "A composite resource claim schema that defines spec.resourceRef should be rejected.",
+			render: ForCompositeResourceClaim,
+			schema: `{"properties":{"spec":{"properties":{"resourceRef":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "resourceRef"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"ClaimResourceRefs": {
+			reason: "spec.resourceRefs is not reserved by composite resource claims.",
+			render: ForCompositeResourceClaim,
+			schema: `{"properties":{"spec":{"properties":{"resourceRefs":{"type":"string"}}}}}`,
+			want:   nil,
+		},
+		"CompositeResourceRef": {
+			reason: "spec.resourceRef is not reserved by composite resources.",
+			render: ForCompositeResource,
+			schema: `{"properties":{"spec":{"properties":{"resourceRef":{"type":"string"}}}}}`,
+			want:   nil,
+		},
+		"ManagementPolicies": {
+			reason: "A schema that defines spec.managementPolicies should be rejected when WithManagementPolicies is enabled.",
+			render: ForCompositeResource,
+			opts:   []Option{WithManagementPolicies()},
+			schema: `{"properties":{"spec":{"properties":{"managementPolicies":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "managementPolicies"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"ObserveOnlyManagementPolicies": {
+			reason: "A schema that defines spec.managementPolicies should be rejected when WithObserveOnlyManagementPolicies is enabled.",
+			render: ForCompositeResource,
+			opts:   []Option{WithObserveOnlyManagementPolicies()},
+			schema: `{"properties":{"spec":{"properties":{"managementPolicies":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "managementPolicies"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"EnforcedDeletePolicy": {
+			reason: "A schema that defines spec.deletionPolicy should be rejected when WithEnforcedDeletePolicy is enabled.",
+			render: ForCompositeResource,
+			opts:   []Option{WithEnforcedDeletePolicy()},
+			schema: `{"properties":{"spec":{"properties":{"deletionPolicy":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "deletionPolicy"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"EnvironmentField": {
+			reason: "A schema that defines spec.environment should be rejected when WithEnvironmentField is enabled.",
+			render: ForCompositeResource,
+			opts:   []Option{WithEnvironmentField()},
+			schema: `{"properties":{"spec":{"properties":{"environment":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "environment"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"TagsField": {
+			reason: "A schema that defines spec.tags should be rejected when WithTagsField is enabled.",
+			render: ForCompositeResource,
+			opts:   []Option{WithTagsField("", "")},
+			schema: `{"properties":{"spec":{"properties":{"tags":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "tags"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"PauseUntilField": {
+			reason: "A schema that defines spec.pausedUntil should be rejected when WithPauseUntilField is enabled.",
+			render: ForCompositeResource,
+			opts:   []Option{WithPauseUntilField()},
+			schema: `{"properties":{"spec":{"properties":{"pausedUntil":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "pausedUntil"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"ClaimPauseUntilField": {
+			reason: "A claim schema that defines spec.pausedUntil should be rejected when WithPauseUntilField is enabled.",
+			render: ForCompositeResourceClaim,
+			opts:   []Option{WithPauseUntilField()},
+			schema: `{"properties":{"spec":{"properties":{"pausedUntil":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "pausedUntil"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"PausedReconcileField": {
+			reason: "A schema that defines spec.pausedReconcile should be rejected when the XRD enables the pausedReconcile field.",
+			render: ForCompositeResource,
+			m:      []xrdModifier{func(d *v1beta1.CompositeResourceDefinition) { d.Spec.PausedReconcileField = true }},
+			schema: `{"properties":{"spec":{"properties":{"pausedReconcile":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "pausedReconcile"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"ManagedFieldsNested": {
+			reason: "A schema that defines spec.crossplane should be rejected when managed fields are nested under it.",
+			render: ForCompositeResource,
+			opts:   []Option{WithManagedFieldsNested()},
+			schema: `{"properties":{"spec":{"properties":{"crossplane":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "crossplane"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"ClaimManagedFieldsNested": {
+			reason: "A claim schema that defines spec.crossplane should be rejected when managed fields are nested under it.",
+			render: ForCompositeResourceClaim,
+			opts:   []Option{WithManagedFieldsNested()},
+			schema: `{"properties":{"spec":{"properties":{"crossplane":{"type":"string"}}}}}`,
+			want:   errors.Wrapf(errors.Errorf(errFmtReservedField, "crossplane"), errFmtInvalidVersion, "v1beta1"),
+		},
+		"ManagedFieldsNestedCompositionRef": {
+			reason: "spec.compositionRef is not reserved when managed fields are nested under spec.crossplane.",
+			render: ForCompositeResource,
+			opts:   []Option{WithManagedFieldsNested()},
+			schema: `{"properties":{"spec":{"properties":{"compositionRef":{"type":"string"}}}}}`,
+			want:   nil,
+		},
+		"OptionDisabled": {
+			reason: "spec.environment is not reserved unless WithEnvironmentField is enabled.",
+			render: ForCompositeResource,
+			schema: `{"properties":{"spec":{"properties":{"environment":{"type":"string"}}}}}`,
+			want:   nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := tc.render(xrd(append([]xrdModifier{withSchema(tc.schema)}, tc.m...)...), tc.opts...)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestServedAPIChanged(t *testing.T) {
+	current, err := ForCompositeResource(xrd())
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	cases := map[string]struct {
+		reason string
+		d      *v1beta1.CompositeResourceDefinition
+		want   bool
+	}{
+		"Unchanged": {
+			reason: "The served API should not change if the XRD does not change.",
+			d:      xrd(),
+			want:   false,
+		},
+		"DescriptionChanged": {
+			reason: "The served API should not change if only a description changes.",
+			d:      xrd(withSchema(`{"properties":{"spec":{"description":"A cool composite."}}}`)),
+			want:   false,
+		},
+		"NamesChanged": {
+			reason: "The served API should change if the names change.",
+			d: xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Names.ShortNames = []string{"cool"}
+			}),
+			want: true,
+		},
+		"ServedVersionsChanged": {
+			reason: "The served API should change if the served versions change.",
+			d: xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Versions = append(d.Spec.Versions, v1beta1.CompositeResourceDefinitionVersion{Name: "v1", Served: true})
+			}),
+			want: true,
+		},
+		"ColumnsChanged": {
+			reason: "The served API should change if the printer columns change.",
+			d: xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.AdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{
+					{Name: "NAME", Type: "string", JSONPath: ".metadata.name"},
+				}
+			}),
+			want: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			desired, err := ForCompositeResource(tc.d)
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			if got := ServedAPIChanged(current, desired); got != tc.want {
+				t.Errorf("\n%s\nServedAPIChanged(...): want %t, got %t", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWithPauseUntilField(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		cols   []extv1.CustomResourceColumnDefinition
+	}{
+		"Composite": {
+			reason: "The composite resource CRD should have a spec.pausedUntil timestamp and a wide PAUSED-UNTIL column.",
+			render: ForCompositeResource,
+			cols:   CompositeResourcePrinterColumns(),
+		},
+		"Claim": {
+			reason: "The composite resource claim CRD should have a spec.pausedUntil timestamp and a wide PAUSED-UNTIL column.",
+			render: ForCompositeResourceClaim,
+			cols:   CompositeResourceClaimPrinterColumns(),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(xrd(), WithPauseUntilField())
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			want := extv1.JSONSchemaProps{
+				Description: "PausedUntil is the RFC 3339 time until which reconciliation of the resource is paused.",
+				Type:        "string",
+				Format:      "date-time",
+			}
+			if diff := cmp.Diff(want, crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["pausedUntil"]); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want pausedUntil, +got pausedUntil:\n%s", tc.reason, diff)
+			}
+			cols := append(tc.cols, extv1.CustomResourceColumnDefinition{
+				Name:     "PAUSED-UNTIL",
+				Type:     "string",
+				Format:   "date-time",
+				JSONPath: ".spec.pausedUntil",
+				Priority: 1,
+			}, AgePrinterColumns()[0])
+			if diff := cmp.Diff(cols, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want columns, +got columns:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithBlockOwnerDeletion(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		want   bool
+	}{
+		"Default": {
+			reason: "The owner reference should block owner deletion by default.",
+			want:   true,
+		},
+		"Block": {
+			reason: "The owner reference should block owner deletion when configured to.",
+			opts:   []Option{WithBlockOwnerDeletion(true)},
+			want:   true,
+		},
+		"DontBlock": {
+			reason: "The owner reference should not block owner deletion when configured not to.",
+			opts:   []Option{WithBlockOwnerDeletion(false)},
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(), tc.opts...)
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			refs := crd.GetOwnerReferences()
+			if len(refs) != 1 || refs[0].BlockOwnerDeletion == nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): want one owner reference with blockOwnerDeletion set, got %v", tc.reason, refs)
+			}
+			if got := *refs[0].BlockOwnerDeletion; got != tc.want {
+				t.Errorf("\n%s\nForCompositeResource(...): want blockOwnerDeletion %t, got %t", tc.reason, tc.want, got)
+			}
+			if got := *refs[0].Controller; !got {
+				t.Errorf("\n%s\nForCompositeResource(...): owner reference should be a controller reference", tc.reason)
+			}
+		})
+	}
+}
+
+func TestMapType(t *testing.T) {
+	granular := "granular"
+	atomic := "atomic"
+
+	t.Run("UserSchema", func(t *testing.T) {
+		schema := `{"properties":{"spec":{"properties":{"config":{"type":"object","x-kubernetes-map-type":"granular","properties":{"a":{"type":"string"}}}}}}}`
+		crd, err := ForCompositeResource(xrd(withSchema(schema)))
+		if err != nil {
+			t.Fatalf("ForCompositeResource(...): %s", err)
+		}
+		want := extv1.JSONSchemaProps{
+			Type:       "object",
+			XMapType:   &granular,
+			Properties: map[string]extv1.JSONSchemaProps{"a": {Type: "string"}},
+		}
+		if diff := cmp.Diff(want, crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["config"]); diff != "" {
+			t.Errorf("ForCompositeResource(...): the map type of user schema objects should be preserved: -want, +got:\n%s", diff)
+		}
+	})
+
+	t.Run("ManagedSpec", func(t *testing.T) {
+		crd, err := ForCompositeResource(xrd(), WithManagedMapType(atomic))
+		if err != nil {
+			t.Fatalf("ForCompositeResource(...): %s", err)
+		}
+		spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+		if diff := cmp.Diff(&atomic, spec.Properties["compositionRef"].XMapType); diff != "" {
+			t.Errorf("ForCompositeResource(...): spec.compositionRef: -want, +got:\n%s", diff)
+		}
+		if diff := cmp.Diff(&atomic, spec.Properties["resourceRefs"].Items.Schema.XMapType); diff != "" {
+			t.Errorf("ForCompositeResource(...): spec.resourceRefs[*]: -want, +got:\n%s", diff)
+		}
+		if spec.XMapType != nil {
+			t.Errorf("ForCompositeResource(...): spec should not have a map type")
+		}
+	})
+
+	t.Run("InvalidMapType", func(t *testing.T) {
+		_, err := ForCompositeResource(xrd(), WithManagedMapType("merge"))
+		if diff := cmp.Diff(errors.Errorf(errFmtInvalidMapType, "merge"), err, test.EquateErrors()); diff != "" {
+			t.Errorf("ForCompositeResource(...): -want error, +got error:\n%s", diff)
+		}
+	})
+}
+
+func TestWithoutStatusSubresource(t *testing.T) {
+	type want struct {
+		subresources *extv1.CustomResourceSubresources
+		columns      []extv1.CustomResourceColumnDefinition
+		err          error
+	}
+
+	scale := &extv1.CustomResourceSubresourceScale{
+		SpecReplicasPath:   ".spec.replicas",
+		StatusReplicasPath: ".status.replicas",
+	}
+
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		d      *v1beta1.CompositeResourceDefinition
+		opts   []Option
+		want   want
+	}{
+		"DefaultColumns": {
+			reason: "Default columns derived from the status should be omitted.",
+			render: ForCompositeResource,
+			d:      xrd(),
+			opts:   []Option{WithPhaseField(nil)},
+			want: want{
+				columns: []extv1.CustomResourceColumnDefinition{CompositeResourcePrinterColumns()[1], AgePrinterColumns()[0]},
+			},
+		},
+		"ClaimDefaultColumns": {
+			reason: "Default claim columns derived from the status should be omitted.",
+			render: ForCompositeResourceClaim,
+			d:      xrd(),
+			want: want{
+				columns: []extv1.CustomResourceColumnDefinition{CompositeResourceClaimPrinterColumns()[1], AgePrinterColumns()[0]},
+			},
+		},
+		"ScaleSubresource": {
+			reason: "The scale subresource should be retained when the status subresource is omitted.",
+			render: ForCompositeResource,
+			d: xrd(withSchema(`{"properties":{"spec":{"properties":{"replicas":{"type":"integer"}}},"status":{"properties":{"replicas":{"type":"integer"}}}}}`), func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Scale = scale
+			}),
+			want: want{
+				subresources: &extv1.CustomResourceSubresources{Scale: scale},
+				columns:      []extv1.CustomResourceColumnDefinition{CompositeResourcePrinterColumns()[1], AgePrinterColumns()[0]},
+			},
+		},
+		"CustomStatusColumn": {
+			reason: "Custom columns derived from the status should be rejected.",
+			render: ForCompositeResource,
+			d: xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.AdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{
+					{Name: "SYNCED", Type: "string", JSONPath: ".status.conditions[?(@.type=='Synced')].status"},
+				}
+			}),
+			want: want{
+				err: errors.Wrapf(field.ErrorList{
+					field.Invalid(field.NewPath("additionalPrinterColumns").Index(1).Child("jsonPath"), ".status.conditions[?(@.type=='Synced')].status", errStatusColumn),
+				}.ToAggregate(), errFmtInvalidVersion, "v1beta1"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(tc.d, append([]Option{WithoutStatusSubresource()}, tc.opts...)...)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nrender(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.subresources, crd.Spec.Versions[0].Subresources); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want subresources, +got subresources:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.columns, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want columns, +got columns:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithColumnOverride(t *testing.T) {
+	synced := extv1.CustomResourceColumnDefinition{
+		Name:     "READY",
+		Type:     "string",
+		JSONPath: ".status.conditions[?(@.type=='Synced')].status",
+	}
+
+	type want struct {
+		columns []extv1.CustomResourceColumnDefinition
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		name   string
+		want   want
+	}{
+		"Override": {
+			reason: "The named default column should be replaced in place.",
+			name:   "READY",
+			want: want{
+				columns: []extv1.CustomResourceColumnDefinition{synced, CompositeResourcePrinterColumns()[1], AgePrinterColumns()[0]},
+			},
+		},
+		"UnknownColumn": {
+			reason: "Overriding a column that does not exist should return an error.",
+			name:   "SYNCED",
+			want: want{
+				err: errors.Errorf(errFmtUnknownColumnOverride, "SYNCED"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(), WithColumnOverride(tc.name, synced))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nForCompositeResource(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.columns, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want columns, +got columns:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestOverrideDefaultPrinterColumns(t *testing.T) {
+	composition := extv1.CustomResourceColumnDefinition{
+		Name:     "COMPOSITION",
+		Type:     "string",
+		JSONPath: ".spec.compositionRef.name",
+		Priority: 1,
+	}
+	region := extv1.CustomResourceColumnDefinition{Name: "REGION", Type: "string", JSONPath: ".spec.parameters.region"}
+
+	d := xrd(withSchema(`{"properties":{"spec":{"properties":{"parameters":{"type":"object","properties":{"region":{"type":"string"}}}}}}}`),
+		func(d *v1beta1.CompositeResourceDefinition) {
+			d.Spec.AdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{region, composition}
+		})
+
+	crd, err := ForCompositeResource(d)
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	want := []extv1.CustomResourceColumnDefinition{CompositeResourcePrinterColumns()[0], composition, region, AgePrinterColumns()[0]}
+	if diff := cmp.Diff(want, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestWithLastReconcileTimeStatus(t *testing.T) {
+	crd, err := ForCompositeResource(xrd(), WithLastReconcileTimeStatus())
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	status := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"]
+	want := extv1.JSONSchemaProps{
+		Description: "LastReconcileTime is the time at which the resource was last reconciled.",
+		Type:        "string",
+		Format:      "date-time",
+	}
+	if diff := cmp.Diff(want, status.Properties["lastReconcileTime"]); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want lastReconcileTime, +got lastReconcileTime:\n%s", diff)
+	}
+	if _, ok := status.Properties["conditions"]; !ok {
+		t.Errorf("ForCompositeResource(...): status.conditions should be present")
+	}
+
+	cols := append(CompositeResourcePrinterColumns(), extv1.CustomResourceColumnDefinition{
+		Name:     "LAST-RECONCILE",
+		Type:     "date",
+		JSONPath: ".status.lastReconcileTime",
+		Priority: 1,
+	}, AgePrinterColumns()[0])
+	if diff := cmp.Diff(cols, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want columns, +got columns:\n%s", diff)
+	}
+}
+
+func TestWithStatusResourceRefs(t *testing.T) {
+	crd, err := ForCompositeResource(xrd(), WithStatusResourceRefs())
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	props := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties
+	status, ok := props["status"].Properties["resourceRefs"]
+	if !ok {
+		t.Fatalf("ForCompositeResource(...): status.resourceRefs should be present")
+	}
+	spec := props["spec"].Properties["resourceRefs"]
+	if diff := cmp.Diff(spec.Items, status.Items); diff != "" {
+		t.Errorf("ForCompositeResource(...): -want spec.resourceRefs items, +got status.resourceRefs items:\n%s", diff)
+	}
+	if _, ok := props["status"].Properties["conditions"]; !ok {
+		t.Errorf("ForCompositeResource(...): status.conditions should be present")
+	}
+}
+
+func TestBuildSchema(t *testing.T) {
+	base := func() *extv1.JSONSchemaProps {
+		return &extv1.JSONSchemaProps{Type: "object", Properties: BaseProps()}
+	}
+	type args struct {
+		base *extv1.JSONSchemaProps
+		user runtime.RawExtension
+	}
+	type want struct {
+		spec map[string]extv1.JSONSchemaProps
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoUserSchema": {
+			reason: "The base schema should be returned unchanged if there is no user schema.",
+			args:   args{base: base()},
+			want:   want{spec: map[string]extv1.JSONSchemaProps{}},
+		},
+		"MergeSpec": {
+			reason: "The spec properties of the user schema should be merged into the base schema.",
+			args: args{
+				base: base(),
+				user: runtime.RawExtension{Raw: []byte(`{"type":"object","properties":{"spec":{"type":"object","properties":{"storageGB":{"type":"integer"}}}}}`)},
+			},
+			want: want{spec: map[string]extv1.JSONSchemaProps{
+				"storageGB": {Type: "integer"},
+			}},
+		},
+		"UntypedRoot": {
+			reason: "A user schema with no type at its root should be treated as an object.",
+			args: args{
+				base: base(),
+				user: runtime.RawExtension{Raw: []byte(`{"properties":{"spec":{"properties":{"storageGB":{"type":"integer"}}}}}`)},
+			},
+			want: want{spec: map[string]extv1.JSONSchemaProps{
+				"storageGB": {Type: "integer"},
+			}},
+		},
+		"BasePrecedence": {
+			reason: "Spec properties of the base schema should not be overwritten by the user schema.",
+			args: args{
+				base: &extv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"spec": {Type: "object", Properties: CompositeResourceSpecProps()},
+					},
+				},
+				user: runtime.RawExtension{Raw: []byte(`{"properties":{"spec":{"properties":{"compositionRef":{"type":"string"}}}}}`)},
+			},
+			want: want{spec: CompositeResourceSpecProps()},
+		},
+		"RootNotObject": {
+			reason: "An error should be returned if the user schema is not an object at its root.",
+			args: args{
+				base: base(),
+				user: runtime.RawExtension{Raw: []byte(`{"type":"string"}`)},
+			},
+			want: want{err: errors.Errorf(errFmtSchemaRootType, "string")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := tc.args.base.DeepCopy()
+			got, err := BuildSchema(tc.args.base, tc.args.user)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nBuildSchema(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.spec, got.Properties["spec"].Properties); diff != "" {
+				t.Errorf("\n%s\nBuildSchema(...): -want spec, +got spec:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(b, tc.args.base); diff != "" {
+				t.Errorf("\n%s\nBuildSchema(...): base schema should not be mutated: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRootPrunesUnknownFields(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"storageGB":{"type":"integer"}}}}}`
+	crd, err := ForCompositeResource(xrd(withSchema(schema)))
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	in := &apiextensions.JSONSchemaProps{}
+	if err := extv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(crd.Spec.Versions[0].Schema.OpenAPIV3Schema, in, nil); err != nil {
+		t.Fatalf("Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(...): %s", err)
+	}
+	s, err := structuralschema.NewStructural(in)
+	if err != nil {
+		t.Fatalf("NewStructural(...): %s", err)
+	}
+
+	obj := map[string]interface{}{
+		"apiVersion": "example.org/v1",
+		"kind":       "CoolComposite",
+		"metadata":   map[string]interface{}{"name": "cool"},
+		"spec":       map[string]interface{}{"storageGB": int64(10)},
+		"status":     map[string]interface{}{},
+		"unexpected": "value",
+	}
+	pruning.Prune(obj, s, true)
+
+	want := map[string]interface{}{
+		"apiVersion": "example.org/v1",
+		"kind":       "CoolComposite",
+		"metadata":   map[string]interface{}{"name": "cool"},
+		"spec":       map[string]interface{}{"storageGB": int64(10)},
+		"status":     map[string]interface{}{},
+	}
+	if diff := cmp.Diff(want, obj); diff != "" {
+		t.Errorf("Prune(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestWithPublishedKeysStatus(t *testing.T) {
+	publishedKeys := extv1.JSONSchemaProps{
+		Description: "PublishedKeys are the connection secret keys this resource publishes.",
+		Type:        "array",
+		Items: &extv1.JSONSchemaPropsOrArray{Schema: &extv1.JSONSchemaProps{
+			Type: "string",
+			Enum: []extv1.JSON{
+				{Raw: []byte(`"username"`)},
+				{Raw: []byte(`"password"`)},
+			},
+		}},
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		want   extv1.JSONSchemaProps
+	}{
+		"Disabled": {
+			reason: "status.connectionDetails should contain only the last published time by default.",
+			want: extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"lastPublishedTime": {Type: "string", Format: "date-time"},
+				},
+			},
+		},
+		"Enabled": {
+			reason: "The published keys should be merged with the last published time.",
+			opts:   []Option{WithPublishedKeysStatus([]string{"username", "password"})},
+			want: extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"lastPublishedTime": {Type: "string", Format: "date-time"},
+					"publishedKeys":     publishedKeys,
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(), tc.opts...)
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			status := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"]
+			if diff := cmp.Diff(tc.want, status.Properties["connectionDetails"]); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if _, ok := status.Properties["conditions"]; !ok {
+				t.Errorf("\n%s\nForCompositeResource(...): status.conditions should be present", tc.reason)
+			}
+		})
+	}
+}
+
+func TestObjectKeywords(t *testing.T) {
+	yes := true
+	atomic := "atomic"
+	required := func(names ...string) extv1.JSONSchemaProps { return extv1.JSONSchemaProps{Required: names} }
+
+	type want struct {
+		props extv1.JSONSchemaProps
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		field  string
+		schema string
+		want   want
+	}{
+		"SpecPreserveUnknownFields": {
+			reason: "x-kubernetes-preserve-unknown-fields should be carried over to the spec.",
+			field:  "spec",
+			schema: `{"properties":{"spec":{"x-kubernetes-preserve-unknown-fields":true}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: &yes}},
+		},
+		"StatusPreserveUnknownFields": {
+			reason: "x-kubernetes-preserve-unknown-fields should be carried over to the status.",
+			field:  "status",
+			schema: `{"properties":{"status":{"x-kubernetes-preserve-unknown-fields":true}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: &yes}},
+		},
+		"SpecMapType": {
+			reason: "x-kubernetes-map-type should be carried over to the spec.",
+			field:  "spec",
+			schema: `{"properties":{"spec":{"x-kubernetes-map-type":"atomic"}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", XMapType: &atomic}},
+		},
+		"StatusMapType": {
+			reason: "x-kubernetes-map-type should be carried over to the status.",
+			field:  "status",
+			schema: `{"properties":{"status":{"x-kubernetes-map-type":"atomic"}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", XMapType: &atomic}},
+		},
+		"SpecOneOf": {
+			reason: "oneOf should be carried over to the spec.",
+			field:  "spec",
+			schema: `{"properties":{"spec":{"oneOf":[{"required":["a"]},{"required":["b"]}]}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", OneOf: []extv1.JSONSchemaProps{required("a"), required("b")}}},
+		},
+		"StatusOneOf": {
+			reason: "oneOf should be carried over to the status.",
+			field:  "status",
+			schema: `{"properties":{"status":{"oneOf":[{"required":["a"]},{"required":["b"]}]}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", OneOf: []extv1.JSONSchemaProps{required("a"), required("b")}}},
+		},
+		"SpecAnyOf": {
+			reason: "anyOf should be carried over to the spec.",
+			field:  "spec",
+			schema: `{"properties":{"spec":{"anyOf":[{"required":["a"]},{"required":["b"]}]}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", AnyOf: []extv1.JSONSchemaProps{required("a"), required("b")}}},
+		},
+		"StatusAnyOf": {
+			reason: "anyOf should be carried over to the status.",
+			field:  "status",
+			schema: `{"properties":{"status":{"anyOf":[{"required":["a"]},{"required":["b"]}]}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", AnyOf: []extv1.JSONSchemaProps{required("a"), required("b")}}},
+		},
+		"SpecNot": {
+			reason: "not should be carried over to the spec.",
+			field:  "spec",
+			schema: `{"properties":{"spec":{"not":{"required":["a"]}}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", Not: &extv1.JSONSchemaProps{Required: []string{"a"}}}},
+		},
+		"StatusNot": {
+			reason: "not should be carried over to the status.",
+			field:  "status",
+			schema: `{"properties":{"status":{"not":{"required":["a"]}}}}`,
+			want:   want{props: extv1.JSONSchemaProps{Type: "object", Not: &extv1.JSONSchemaProps{Required: []string{"a"}}}},
+		},
+		"SpecAdditionalProperties": {
+			reason: "additionalProperties cannot be combined with the spec properties Crossplane adds, and should be rejected.",
+			field:  "spec",
+			schema: `{"properties":{"spec":{"additionalProperties":{"type":"string"}}}}`,
+			want:   want{err: errors.Errorf(errFmtAdditionalProperties, "spec")},
+		},
+		"StatusAdditionalProperties": {
+			reason: "additionalProperties cannot be combined with the status properties Crossplane adds, and should be rejected.",
+			field:  "status",
+			schema: `{"properties":{"status":{"additionalProperties":{"type":"string"}}}}`,
+			want:   want{err: errors.Errorf(errFmtAdditionalProperties, "status")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := BuildSchema(&extv1.JSONSchemaProps{Type: "object", Properties: BaseProps()}, runtime.RawExtension{Raw: []byte(tc.schema)})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nBuildSchema(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			// Only the object level keywords are of interest.
+			p := got.Properties[tc.field]
+			p.Properties = nil
+			if diff := cmp.Diff(tc.want.props, p); diff != "" {
+				t.Errorf("\n%s\nBuildSchema(...): -want %s, +got %s:\n%s", tc.reason, tc.field, tc.field, diff)
+			}
+		})
+	}
+}
+
+func TestSpecObjectConstraints(t *testing.T) {
+	schema := `{"properties":{"spec":{"description":"A cool spec.","required":["storageGB","compositionRef","resourceRef"],"minProperties":1,"maxProperties":10,"properties":{"storageGB":{"type":"integer"}}}}}`
+	one, ten := int64(1), int64(10)
+
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		want   []string
+	}{
+		"Composite": {
+			reason: "Composite resources should honor the required user fields, but not the reserved compositionRef.",
+			render: ForCompositeResource,
+			want:   []string{"storageGB", "resourceRef"},
+		},
+		"Claim": {
+			reason: "Composite resource claims should honor the required user fields, but not the reserved compositionRef or resourceRef.",
+			render: ForCompositeResourceClaim,
+			want:   []string{"storageGB"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(xrd(withSchema(schema)))
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+			if diff := cmp.Diff(tc.want, spec.Required); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want required, +got required:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff("A cool spec.", spec.Description); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want description, +got description:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(&one, spec.MinProperties); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want minProperties, +got minProperties:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(&ten, spec.MaxProperties); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want maxProperties, +got maxProperties:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestScaleSubresource(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"replicas":{"type":"integer"},"size":{"type":"string"}}},"status":{"properties":{"replicas":{"type":"integer"},"selector":{"type":"string"}}}}}`
+	selector := ".status.selector"
+
+	type want struct {
+		scale *extv1.CustomResourceSubresourceScale
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		scale  *extv1.CustomResourceSubresourceScale
+		want   want
+	}{
+		"NoScale": {
+			reason: "No scale subresource should be generated by default.",
+		},
+		"Valid": {
+			reason: "A scale subresource whose paths resolve to fields of the expected types should be generated.",
+			scale: &extv1.CustomResourceSubresourceScale{
+				SpecReplicasPath:   ".spec.replicas",
+				StatusReplicasPath: ".status.replicas",
+				LabelSelectorPath:  &selector,
+			},
+			want: want{
+				scale: &extv1.CustomResourceSubresourceScale{
+					SpecReplicasPath:   ".spec.replicas",
+					StatusReplicasPath: ".status.replicas",
+					LabelSelectorPath:  &selector,
+				},
+			},
+		},
+		"InvalidPaths": {
+			reason: "A scale subresource whose paths do not resolve to fields of the expected types should be rejected.",
+			scale: &extv1.CustomResourceSubresourceScale{
+				SpecReplicasPath:   ".spec.size",
+				StatusReplicasPath: ".status.count",
+			},
+			want: want{
+				err: errors.Wrapf(field.ErrorList{
+					field.Invalid(field.NewPath("subresources", "scale", "specReplicasPath"), ".spec.size", errScaleReplicasPath),
+					field.Invalid(field.NewPath("subresources", "scale", "statusReplicasPath"), ".status.count", errScaleReplicasPath),
+				}.ToAggregate(), errFmtInvalidVersion, "v1beta1"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := xrd(withSchema(schema), func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Scale = tc.scale
+			})
+			crd, err := ForCompositeResource(d)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nForCompositeResource(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.scale, crd.Spec.Versions[0].Subresources.Scale); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want scale, +got scale:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestListEnums(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"engineVersion":{"enum":["5.6","5.7"],"type":"string"},"storageGB":{"type":"integer"}}}}}`
+	engineVersion := []extv1.JSON{
+		{Raw: []byte(`"5.6"`)},
+		{Raw: []byte(`"5.7"`)},
+	}
+
+	type want struct {
+		enums map[string][]extv1.JSON
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		d      *v1beta1.CompositeResourceDefinition
+		opts   []Option
+		want   want
+	}{
+		"UserEnum": {
+			reason: "Enums declared by the user's schema should be listed by path.",
+			d:      xrd(withSchema(schema)),
+			want: want{
+				enums: map[string][]extv1.JSON{"spec.engineVersion": engineVersion},
+			},
+		},
+		"ManagedEnum": {
+			reason: "Enums added by Crossplane should be listed alongside those declared by the user's schema.",
+			d:      xrd(withSchema(schema)),
+			opts:   []Option{WithManagementPolicies()},
+			want: want{
+				enums: map[string][]extv1.JSON{
+					"spec.engineVersion":         engineVersion,
+					"spec.managementPolicies[*]": ManagementPoliciesSpecProps()["managementPolicies"].Items.Schema.Enum,
+				},
+			},
+		},
+		"InvalidSchema": {
+			reason: "An error should be returned if the CRD cannot be derived.",
+			d:      xrd(withSchema(`{"type":"string"}`)),
+			want: want{
+				err: errors.Wrapf(errors.Errorf(errFmtSchemaRootType, "string"), errFmtInvalidVersion, "v1beta1"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ListEnums(tc.d, tc.opts...)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nListEnums(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.enums, got); diff != "" {
+				t.Errorf("\n%s\nListEnums(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAgeColumn(t *testing.T) {
+	age := extv1.CustomResourceColumnDefinition{Name: "AGE", Type: "date", JSONPath: ".metadata.creationTimestamp", Priority: 1}
+
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		d      *v1beta1.CompositeResourceDefinition
+		want   []extv1.CustomResourceColumnDefinition
+	}{
+		"Composite": {
+			reason: "An AGE column should be appended to the columns of a composite resource by default.",
+			render: ForCompositeResource,
+			d:      xrd(),
+			want:   append(CompositeResourcePrinterColumns(), AgePrinterColumns()...),
+		},
+		"Claim": {
+			reason: "An AGE column should be appended to the columns of a composite resource claim by default.",
+			render: ForCompositeResourceClaim,
+			d:      xrd(),
+			want:   append(CompositeResourceClaimPrinterColumns(), AgePrinterColumns()...),
+		},
+		"CompositeOmitted": {
+			reason: "No AGE column should be appended to the columns of a composite resource if it is omitted.",
+			render: ForCompositeResource,
+			d:      xrd(func(d *v1beta1.CompositeResourceDefinition) { d.Spec.OmitAgeColumn = true }),
+			want:   CompositeResourcePrinterColumns(),
+		},
+		"ClaimOmitted": {
+			reason: "No AGE column should be appended to the columns of a composite resource claim if it is omitted.",
+			render: ForCompositeResourceClaim,
+			d:      xrd(func(d *v1beta1.CompositeResourceDefinition) { d.Spec.OmitAgeColumn = true }),
+			want:   CompositeResourceClaimPrinterColumns(),
+		},
+		"CustomAge": {
+			reason: "A custom AGE column should not be duplicated.",
+			render: ForCompositeResource,
+			d: xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.AdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{age}
+			}),
+			want: append(CompositeResourcePrinterColumns(), age),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(tc.d)
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestStrictEmptySpec(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		opts   []Option
+		d      *v1beta1.CompositeResourceDefinition
+		want   error
+	}{
+		"NotStrict": {
+			reason: "A schema with an empty spec should be allowed when strict validation is disabled.",
+			render: ForCompositeResource,
+			d:      xrd(),
+		},
+		"Strict": {
+			reason: "A schema with an empty spec should be allowed when strict validation is enabled, because Crossplane injects its managed spec props.",
+			render: ForCompositeResource,
+			opts:   []Option{WithStrictValidation()},
+			d:      xrd(),
+		},
+		"StrictNested": {
+			reason: "A schema with an empty spec should be allowed when strict validation is enabled and managed spec props are nested.",
+			render: ForCompositeResource,
+			opts:   []Option{WithStrictValidation(), WithManagedFieldsNested()},
+			d:      xrd(),
+		},
+		"StrictWithSpec": {
+			reason: "A schema with spec properties should be allowed when strict validation is enabled.",
+			render: ForCompositeResource,
+			opts:   []Option{WithStrictValidation()},
+			d:      xrd(withSchema(`{"properties":{"spec":{"properties":{"size":{"type":"string"}}}}}`)),
+		},
+		"StrictClaim": {
+			reason: "A claim schema with an empty spec should be allowed when strict validation is enabled, because Crossplane injects its managed spec props.",
+			render: ForCompositeResourceClaim,
+			opts:   []Option{WithStrictValidation()},
+			d:      xrd(),
+		},
+		"StrictShell": {
+			reason: "A shell, which omits the schema, should be allowed when strict validation is enabled.",
+			render: ForCompositeResourceShell,
+			opts:   []Option{WithStrictValidation()},
+			d:      xrd(withSchema(`{"properties":{"spec":{"properties":{"size":{"type":"string"}}}}}`)),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := tc.render(tc.d, tc.opts...)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestStrictUIDPattern(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		want   string
+	}{
+		"NotStrict": {
+			reason: "The uid of managed and observed references should be a free string by default.",
+			want:   "",
+		},
+		"Strict": {
+			reason: "The uid of managed and observed references should be constrained to a UUID under strict validation.",
+			opts:   []Option{WithStrictValidation()},
+			want:   uidPattern,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(withSchema(`{"properties":{"spec":{"properties":{"size":{"type":"string"}}}}}`)), append(tc.opts, WithStatusResourceRefs())...)
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			refs := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["resourceRefs"]
+			if diff := cmp.Diff(tc.want, refs.Items.Schema.Properties["uid"].Pattern); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want spec pattern, +got spec pattern:\n%s", tc.reason, diff)
+			}
+			refs = crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"].Properties["resourceRefs"]
+			if diff := cmp.Diff(tc.want, refs.Items.Schema.Properties["uid"].Pattern); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want status pattern, +got status pattern:\n%s", tc.reason, diff)
+			}
+		})
+	}
+
+	re := regexp.MustCompile(uidPattern)
+	if !re.MatchString("4c5a8b2e-7f3d-4e21-9a6b-0c1d2e3f4a5b") {
+		t.Errorf("uidPattern should match a UUID")
+	}
+	if re.MatchString("not-a-uuid") {
+		t.Errorf("uidPattern should not match a non-UUID")
+	}
+}
+
+func TestClaimName(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		d      *v1beta1.CompositeResourceDefinition
+		want   error
+	}{
+		"ValidName": {
+			reason: "A claim CRD should be named <claimNames.plural>.<group>.",
+			d:      xrd(),
+		},
+		"MismatchedGroup": {
+			reason: "A claim CRD should not be derived from a definition whose name does not match its group.",
+			d: xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Group = "example.net"
+			}),
+			want: errors.Errorf(errFmtUnexpectedName, "coolcomposites.example.org", "coolcomposites.example.net"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResourceClaim(tc.d)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nForCompositeResourceClaim(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff("coolclaims.example.org", crd.GetName()); diff != "" {
+				t.Errorf("\n%s\nForCompositeResourceClaim(...): -want name, +got name:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConversion(t *testing.T) {
+	port := int32(8443)
+	svc := &extv1.ServiceReference{Namespace: "crossplane-system", Name: "converter", Port: &port}
+
+	type want struct {
+		conversion *extv1.CustomResourceConversion
+		err        error
+	}
+
+	cases := map[string]struct {
+		reason     string
+		conversion *v1beta1.CompositeResourceConversion
+		want       want
+	}{
+		"Default": {
+			reason: "Versions should not be converted by default.",
+			want: want{
+				conversion: &extv1.CustomResourceConversion{Strategy: extv1.NoneConverter},
+			},
+		},
+		"None": {
+			reason: "The None strategy should be honored.",
+			conversion: &v1beta1.CompositeResourceConversion{
+				Strategy: extv1.NoneConverter,
+				Service:  svc,
+			},
+			want: want{
+				conversion: &extv1.CustomResourceConversion{Strategy: extv1.NoneConverter},
+			},
+		},
+		"Webhook": {
+			reason: "The Webhook strategy should call the configured service.",
+			conversion: &v1beta1.CompositeResourceConversion{
+				Strategy: extv1.WebhookConverter,
+				Service:  svc,
+				CABundle: []byte("cool-ca"),
+			},
+			want: want{
+				conversion: &extv1.CustomResourceConversion{
+					Strategy: extv1.WebhookConverter,
+					Webhook: &extv1.WebhookConversion{
+						ClientConfig: &extv1.WebhookClientConfig{
+							Service:  svc,
+							CABundle: []byte("cool-ca"),
+						},
+						ConversionReviewVersions: []string{"v1", "v1beta1"},
+					},
+				},
+			},
+		},
+		"WebhookWithoutService": {
+			reason: "The Webhook strategy should require a service name.",
+			conversion: &v1beta1.CompositeResourceConversion{
+				Strategy: extv1.WebhookConverter,
+				Service:  &extv1.ServiceReference{Namespace: "crossplane-system"},
+			},
+			want: want{
+				err: errors.New(errConversionService),
+			},
+		},
+		"UnknownStrategy": {
+			reason: "Unknown strategies should be rejected.",
+			conversion: &v1beta1.CompositeResourceConversion{
+				Strategy: "Magic",
+			},
+			want: want{
+				err: errors.Errorf(errFmtConversionStrategy, "Magic"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Conversion = tc.conversion
+			})
+			for _, render := range []func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error){
+				ForCompositeResource,
+				ForCompositeResourceClaim,
+			} {
+				crd, err := render(d)
+				if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+					t.Fatalf("\n%s\nrender(...): -want error, +got error:\n%s", tc.reason, diff)
+				}
+				if err != nil {
+					continue
+				}
+				if diff := cmp.Diff(tc.want.conversion, crd.Spec.Conversion); diff != "" {
+					t.Errorf("\n%s\nrender(...): -want, +got:\n%s", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDescriptions(t *testing.T) {
+	schema := `{"description":"A cool schema.","properties":{"spec":{"description":"A cool spec.","properties":{"size":{"type":"string","description":"The size."}}}}}`
+
+	type want struct {
+		root string
+		spec string
+		size string
+	}
+
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		d      *v1beta1.CompositeResourceDefinition
+		want   want
+	}{
+		"CompositeSchemaDescription": {
+			reason: "The descriptions of the user schema should be preserved.",
+			render: ForCompositeResource,
+			d:      xrd(withSchema(schema)),
+			want:   want{root: "A cool schema.", spec: "A cool spec.", size: "The size."},
+		},
+		"CompositeXRDDescription": {
+			reason: "The description of the XRD should take precedence at the root of the schema.",
+			render: ForCompositeResource,
+			d: xrd(withSchema(schema), func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Description = "A cool composite."
+			}),
+			want: want{root: "A cool composite.", spec: "A cool spec.", size: "The size."},
+		},
+		"ClaimXRDDescription": {
+			reason: "The description of the XRD should be used for claims too.",
+			render: ForCompositeResourceClaim,
+			d: xrd(withSchema(schema), func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Description = "A cool composite."
+			}),
+			want: want{root: "A cool composite.", spec: "A cool spec.", size: "The size."},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := tc.render(tc.d)
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			s := crd.Spec.Versions[0].Schema.OpenAPIV3Schema
+			got := want{
+				root: s.Description,
+				spec: s.Properties["spec"].Description,
+				size: s.Properties["spec"].Properties["size"].Description,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPreserveUnknownFieldsSubtree(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"config":{"type":"object","x-kubernetes-preserve-unknown-fields":true}}}}}`
+
+	for name, render := range map[string]func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error){
+		"Composite": ForCompositeResource,
+		"Claim":     ForCompositeResourceClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			crd, err := render(xrd(withSchema(schema)))
+			if err != nil {
+				t.Fatalf("render(...): %s", err)
+			}
+			if crd.Spec.PreserveUnknownFields {
+				t.Errorf("render(...): spec.preserveUnknownFields must be false")
+			}
+
+			in := &apiextensions.JSONSchemaProps{}
+			if err := extv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(crd.Spec.Versions[0].Schema.OpenAPIV3Schema, in, nil); err != nil {
+				t.Fatalf("Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(...): %s", err)
+			}
+			s, err := structuralschema.NewStructural(in)
+			if err != nil {
+				t.Fatalf("NewStructural(...): %s", err)
+			}
+
+			obj := map[string]interface{}{
+				"spec": map[string]interface{}{
+					"config":     map[string]interface{}{"region": "us-west-2", "nested": map[string]interface{}{"cool": true}},
+					"unexpected": "value",
+				},
+			}
+			pruning.Prune(obj, s, true)
+
+			want := map[string]interface{}{
+				"spec": map[string]interface{}{
+					"config": map[string]interface{}{"region": "us-west-2", "nested": map[string]interface{}{"cool": true}},
+				},
+			}
+			if diff := cmp.Diff(want, obj); diff != "" {
+				t.Errorf("Prune(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"storageGB":{"type":"integer","default":20},"parameters":{"type":"object","properties":{"region":{"type":"string","default":"us-west-2"}}}}}}}`
+
+	for name, render := range map[string]func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error){
+		"Composite": ForCompositeResource,
+		"Claim":     ForCompositeResourceClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			crd, err := render(xrd(withSchema(schema)))
+			if err != nil {
+				t.Fatalf("render(...): %s", err)
+			}
+
+			spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+			if diff := cmp.Diff(&extv1.JSON{Raw: []byte(`20`)}, spec.Properties["storageGB"].Default); diff != "" {
+				t.Errorf("render(...): -want spec.storageGB default, +got spec.storageGB default:\n%s", diff)
+			}
+			if diff := cmp.Diff(&extv1.JSON{Raw: []byte(`"us-west-2"`)}, spec.Properties["parameters"].Properties["region"].Default); diff != "" {
+				t.Errorf("render(...): -want spec.parameters.region default, +got spec.parameters.region default:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCanStoreLosslessly(t *testing.T) {
+	object := func(props map[string]extv1.JSONSchemaProps) *extv1.JSONSchemaProps {
+		return &extv1.JSONSchemaProps{Type: "object", Properties: props}
+	}
+	storage := object(map[string]extv1.JSONSchemaProps{
+		"spec": *object(map[string]extv1.JSONSchemaProps{
+			"storageGB": {Type: "integer"},
+			"config":    {Type: "object", XPreserveUnknownFields: func() *bool { b := true; return &b }()},
+		}),
+	})
+
+	type args struct {
+		versions   []*extv1.JSONSchemaProps
+		storageIdx int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   error
+	}{
+		"Subset": {
+			reason: "A served version whose fields are all present in the storage version should be stored losslessly.",
+			args: args{
+				versions: []*extv1.JSONSchemaProps{
+					object(map[string]extv1.JSONSchemaProps{
+						"spec": *object(map[string]extv1.JSONSchemaProps{
+							"storageGB": {Type: "integer"},
+							"config":    *object(map[string]extv1.JSONSchemaProps{"region": {Type: "string"}}),
+						}),
+					}),
+					storage,
+				},
+				storageIdx: 1,
+			},
+		},
+		"ExtraField": {
+			reason: "A served version with a field absent from the storage version should be flagged.",
+			args: args{
+				versions: []*extv1.JSONSchemaProps{
+					storage,
+					object(map[string]extv1.JSONSchemaProps{
+						"spec": *object(map[string]extv1.JSONSchemaProps{
+							"storageGB":     {Type: "integer"},
+							"engineVersion": {Type: "string"},
+						}),
+					}),
+				},
+				storageIdx: 0,
+			},
+			want: field.ErrorList{
+				field.Forbidden(field.NewPath("versions").Index(1).Child("spec", "engineVersion"), errLossyField),
+			}.ToAggregate(),
+		},
+		"StorageIndexOutOfRange": {
+			reason: "A storage index that does not identify a version should return an error.",
+			args: args{
+				versions:   []*extv1.JSONSchemaProps{storage},
+				storageIdx: 1,
+			},
+			want: errors.Errorf(errFmtStorageIndex, 1),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := CanStoreLosslessly(tc.args.versions, tc.args.storageIdx)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCanStoreLosslessly(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCompositeScope(t *testing.T) {
+	type want struct {
+		scope      extv1.ResourceScope
+		claimRef   []string
+		connSecret []string
+		err        error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		scope   extv1.ResourceScope
+		noClaim bool
+		render  func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		want    want
+	}{
+		"Default": {
+			reason: "Composite resources should be cluster scoped by default.",
+			want: want{
+				scope:      extv1.ClusterScoped,
+				claimRef:   []string{"apiVersion", "kind", "namespace", "name"},
+				connSecret: []string{"name", "namespace"},
+			},
+		},
+		"Cluster": {
+			reason: "Cluster scoped composite resources should reference the namespace of their claim and connection secret.",
+			scope:  extv1.ClusterScoped,
+			want: want{
+				scope:      extv1.ClusterScoped,
+				claimRef:   []string{"apiVersion", "kind", "namespace", "name"},
+				connSecret: []string{"name", "namespace"},
+			},
+		},
+		"Namespaced": {
+			reason:  "Namespaced composite resources cannot be claimed, but should reference the namespace of their connection secret.",
+			scope:   extv1.NamespaceScoped,
+			noClaim: true,
+			want: want{
+				scope:      extv1.NamespaceScoped,
+				connSecret: []string{"name", "namespace"},
+			},
+		},
+		"NamespacedWithClaimNames": {
+			reason: "Namespaced composite resources that specify claim names should be rejected.",
+			scope:  extv1.NamespaceScoped,
+			want: want{
+				err: errors.New(errNamespacedClaim),
+			},
+		},
+		"NamespacedClaim": {
+			reason: "A claim CRD should not be derived for a namespaced composite resource.",
+			scope:  extv1.NamespaceScoped,
+			render: ForCompositeResourceClaim,
+			want: want{
+				err: errors.New(errNamespacedClaim),
+			},
+		},
+		"Unknown": {
+			reason: "An unknown scope should return an error.",
+			scope:  extv1.ResourceScope("Galaxy"),
+			want: want{
+				err: errors.Errorf(errFmtScope, "Galaxy"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			render := tc.render
+			if render == nil {
+				render = ForCompositeResource
+			}
+			crd, err := render(xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Scope = tc.scope
+				if tc.noClaim {
+					d.Spec.ClaimNames = nil
+				}
+			}))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nForCompositeResource(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.scope, crd.Spec.Scope); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want scope, +got scope:\n%s", tc.reason, diff)
+			}
+			spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+			if _, ok := spec.Properties["claimRef"]; ok != (tc.want.claimRef != nil) {
+				t.Errorf("\n%s\nForCompositeResource(...): spec.claimRef present: %t", tc.reason, ok)
+			}
+			if diff := cmp.Diff(tc.want.claimRef, spec.Properties["claimRef"].Required); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want claimRef required, +got claimRef required:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.connSecret, spec.Properties["writeConnectionSecretToRef"].Required); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want writeConnectionSecretToRef required, +got writeConnectionSecretToRef required:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCategories(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		claim  []string
+		want   []string
+	}{
+		"Composite": {
+			reason: "The composite resource CRD should include the categories of the XRD.",
+			render: ForCompositeResource,
+			want:   []string{"crossplane", "all", CategoryComposite},
+		},
+		"ClaimInherited": {
+			reason: "The claim CRD should inherit the categories of the composite resource if it specifies none.",
+			render: ForCompositeResourceClaim,
+			want:   []string{"crossplane", "all", CategoryClaim},
+		},
+		"ClaimOverridden": {
+			reason: "The claim CRD should use its own categories if it specifies any.",
+			render: ForCompositeResourceClaim,
+			claim:  []string{"databases"},
+			want:   []string{"databases", CategoryClaim},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Names.Categories = []string{"crossplane", "all"}
+				d.Spec.ClaimNames.Categories = tc.claim
+			})
+			crd, err := tc.render(d)
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, crd.Spec.Names.Categories); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestShortNames(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		render func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error)
+		want   []string
+	}{
+		"Composite": {
+			reason: "The composite resource CRD should include the deduplicated short names of the XRD.",
+			render: ForCompositeResource,
+			want:   []string{"xdb", "xdbs"},
+		},
+		"Claim": {
+			reason: "The claim CRD should include the deduplicated short names of the XRD's claim names.",
+			render: ForCompositeResourceClaim,
+			want:   []string{"db"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Names.ShortNames = []string{"xdb", "xdbs", "xdb"}
+				d.Spec.ClaimNames.ShortNames = []string{"db", "db"}
+			})
+			crd, err := tc.render(d)
+			if err != nil {
+				t.Fatalf("\n%s\nrender(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, crd.Spec.Names.ShortNames); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithSpecFieldsAnnotation(t *testing.T) {
+	t.Run("Fields", func(t *testing.T) {
+		schema := `{"properties":{"spec":{"properties":{"engineVersion":{"type":"string"},"storageGB":{"type":"integer"}}}}}`
+		crd, err := ForCompositeResource(xrd(withSchema(schema)), WithSpecFieldsAnnotation())
+		if err != nil {
+			t.Fatalf("ForCompositeResource(...): %s", err)
+		}
+
+		props := CompositeResourceSpecProps()
+		props["engineVersion"] = extv1.JSONSchemaProps{}
+		props["storageGB"] = extv1.JSONSchemaProps{}
+		want := strings.Join(sortedKeys(props), ",")
+		if diff := cmp.Diff(want, crd.GetAnnotations()[AnnotationKeySpecFields]); diff != "" {
+			t.Errorf("ForCompositeResource(...): -want, +got:\n%s", diff)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		fields := make([]string, 1000)
+		for i := range fields {
+			fields[i] = fmt.Sprintf(`"field%04d":{"type":"string"}`, i)
+		}
+		schema := `{"properties":{"spec":{"properties":{` + strings.Join(fields, ",") + `}}}}`
+		crd, err := ForCompositeResource(xrd(withSchema(schema)), WithSpecFieldsAnnotation())
+		if err != nil {
+			t.Fatalf("ForCompositeResource(...): %s", err)
+		}
+
+		got := crd.GetAnnotations()[AnnotationKeySpecFields]
+		if len(got) != maxSpecFieldsLength || !strings.HasSuffix(got, "...") {
+			t.Errorf("ForCompositeResource(...): want %d characters ending in an ellipsis, got %d: %q", maxSpecFieldsLength, len(got), got[len(got)-10:])
+		}
+	})
+}
+
+func TestPausedReconcileField(t *testing.T) {
+	type want struct {
+		prop    *extv1.JSONSchemaProps
+		columns []extv1.CustomResourceColumnDefinition
+	}
+
+	cases := map[string]struct {
+		reason string
+		field  bool
+		opts   []Option
+		want   want
+	}{
+		"Disabled": {
+			reason: "The pausedReconcile field and PAUSED column should not be present by default.",
+			want: want{
+				columns: append(CompositeResourcePrinterColumns(), AgePrinterColumns()...),
+			},
+		},
+		"Enabled": {
+			reason: "The pausedReconcile field and PAUSED column should be present when enabled by the XRD.",
+			field:  true,
+			want: want{
+				prop: &extv1.JSONSchemaProps{
+					Description: "PausedReconcile specifies whether reconciliation of the resource is paused.",
+					Type:        "boolean",
+				},
+				columns: append(append(CompositeResourcePrinterColumns(), PausedReconcilePrinterColumns()...), AgePrinterColumns()...),
+			},
+		},
+		"EnabledNested": {
+			reason: "The PAUSED column should refer to the pausedReconcile field when managed fields are nested.",
+			field:  true,
+			opts:   []Option{WithManagedFieldsNested()},
+			want: want{
+				prop: &extv1.JSONSchemaProps{
+					Description: "PausedReconcile specifies whether reconciliation of the resource is paused.",
+					Type:        "boolean",
+				},
+				columns: []extv1.CustomResourceColumnDefinition{
+					CompositeResourcePrinterColumns()[0],
+					{Name: "COMPOSITION", Type: "string", JSONPath: ".spec.crossplane.compositionRef.name"},
+					PausedReconcilePrinterColumns()[0],
+					AgePrinterColumns()[0],
+				},
+			},
+		},
+		"Overridden": {
+			reason: "The PAUSED column should be subject to column overrides.",
+			field:  true,
+			opts:   []Option{WithColumnOverride("PAUSED", extv1.CustomResourceColumnDefinition{Name: "PAUSED", Type: "boolean", JSONPath: ".spec.pausedReconcile", Priority: 1})},
+			want: want{
+				prop: &extv1.JSONSchemaProps{
+					Description: "PausedReconcile specifies whether reconciliation of the resource is paused.",
+					Type:        "boolean",
+				},
+				columns: append(append(CompositeResourcePrinterColumns(), extv1.CustomResourceColumnDefinition{Name: "PAUSED", Type: "boolean", JSONPath: ".spec.pausedReconcile", Priority: 1}), AgePrinterColumns()...),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd, err := ForCompositeResource(xrd(func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.PausedReconcileField = tc.field
+			}), tc.opts...)
+			if err != nil {
+				t.Fatalf("\n%s\nForCompositeResource(...): %s", tc.reason, err)
+			}
+			var got *extv1.JSONSchemaProps
+			if p, ok := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["pausedReconcile"]; ok {
+				got = &p
+			}
+			if diff := cmp.Diff(tc.want.prop, got); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want pausedReconcile, +got pausedReconcile:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.columns, crd.Spec.Versions[0].AdditionalPrinterColumns); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want columns, +got columns:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestIdempotentGeneration(t *testing.T) {
+	schema := `{"properties":{"spec":{"required":["storageGB"],"properties":{"engineVersion":{"type":"string","enum":["5.6","5.7"]},"storageGB":{"type":"integer","default":20},"replicas":{"type":"integer"}}},"status":{"properties":{"replicas":{"type":"integer"}}}}}`
+	d := xrd(withSchema(schema), func(d *v1beta1.CompositeResourceDefinition) {
+		d.SetLabels(map[string]string{"cool": "very"})
+		d.SetAnnotations(map[string]string{"cool": "very"})
+		d.Spec.Names.Categories = make([]string, 1, 4)
+		d.Spec.Names.Categories[0] = "crossplane"
+		d.Spec.Names.ShortNames = []string{"cc"}
+		d.Spec.ClaimNames.ShortNames = []string{"cl"}
+		d.Spec.AdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{{Name: "STORAGE", Type: "integer", JSONPath: ".spec.storageGB"}}
+		d.Spec.Scale = &extv1.CustomResourceSubresourceScale{SpecReplicasPath: ".spec.replicas", StatusReplicasPath: ".status.replicas"}
+		d.Spec.PausedReconcileField = true
+		d.Spec.Versions = append(d.Spec.Versions, v1beta1.CompositeResourceDefinitionVersion{
+			Name:   "v1alpha1",
+			Served: true,
+			Schema: d.Spec.Versions[0].Schema.DeepCopy(),
+		})
+	})
+	opts := []Option{WithManagedFieldsNested(), WithXRDUIDAnnotation(), WithSpecFieldsAnnotation(), WithPhaseField(nil)}
+
+	for name, render := range map[string]func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error){
+		"Composite": ForCompositeResource,
+		"Claim":     ForCompositeResourceClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			in := d.DeepCopy()
+
+			first, err := render(in, opts...)
+			if err != nil {
+				t.Fatalf("render(...): %s", err)
+			}
+			second, err := render(in, opts...)
+			if err != nil {
+				t.Fatalf("render(...): %s", err)
+			}
+			if diff := cmp.Diff(first, second); diff != "" {
+				t.Errorf("render(...): -first, +second:\n%s", diff)
+			}
+
+			// Changes to a generated CRD must not affect the XRD.
+			first.GetLabels()["cool"] = "not"
+			first.GetAnnotations()["cool"] = "not"
+			first.Spec.Names.Categories[0] = "changed"
+			if diff := cmp.Diff(d, in); diff != "" {
+				t.Errorf("render(...): -want unmodified XRD, +got XRD:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"email":{"type":"string","format":"email"},"network":{"type":"object","properties":{"address":{"type":"string","format":"ipv4"},"owner":{"type":"object","properties":{"id":{"type":"string","format":"uuid"}}}}}}}}}`
+
+	for name, render := range map[string]func(d *v1beta1.CompositeResourceDefinition, o ...Option) (*extv1.CustomResourceDefinition, error){
+		"Composite": ForCompositeResource,
+		"Claim":     ForCompositeResourceClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			crd, err := render(xrd(withSchema(schema)), WithStrictValidation())
+			if err != nil {
+				t.Fatalf("render(...): %s", err)
+			}
+
+			spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+			network := spec.Properties["network"]
+			got := map[string]string{
+				"spec.email":            spec.Properties["email"].Format,
+				"spec.network.address":  network.Properties["address"].Format,
+				"spec.network.owner.id": network.Properties["owner"].Properties["id"].Format,
+			}
+			want := map[string]string{
+				"spec.email":            "email",
+				"spec.network.address":  "ipv4",
+				"spec.network.owner.id": "uuid",
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("render(...): -want formats, +got formats:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidationConstraints(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"storageGB":{"type":"integer","minimum":1,"maximum":1000},"parameters":{"type":"object","properties":{"name":{"type":"string","pattern":"^[a-z][a-z0-9-]*$","minLength":3,"maxLength":63}}}}}}}`
+
+	crd, err := ForCompositeResource(xrd(withSchema(schema)))
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	cases := map[string]struct {
+		reason string
+		got    extv1.JSONSchemaProps
+		want   extv1.JSONSchemaProps
+	}{
+		"BoundedInteger": {
+			reason: "The numeric bounds of an integer should be carried through.",
+			got:    spec.Properties["storageGB"],
+			want: extv1.JSONSchemaProps{
+				Type:    "integer",
+				Minimum: func() *float64 { f := float64(1); return &f }(),
+				Maximum: func() *float64 { f := float64(1000); return &f }(),
+			},
+		},
+		"PatternedString": {
+			reason: "The pattern and length bounds of a nested string should be carried through.",
+			got:    spec.Properties["parameters"].Properties["name"],
+			want: extv1.JSONSchemaProps{
+				Type:      "string",
+				Pattern:   "^[a-z][a-z0-9-]*$",
+				MinLength: func() *int64 { i := int64(3); return &i }(),
+				MaxLength: func() *int64 { i := int64(63); return &i }(),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.got); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
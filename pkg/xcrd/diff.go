@@ -0,0 +1,209 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xcrd
+
+import (
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	errNilCRD        = "cannot diff a nil CustomResourceDefinition"
+	errFmtDiffKinds  = "cannot diff CustomResourceDefinitions of different kinds %q and %q"
+	errFmtDiffGroups = "cannot diff CustomResourceDefinitions of different groups %q and %q"
+)
+
+// An IncompatibleChangeType is a kind of backward incompatible change to the
+// schema of a CustomResourceDefinition.
+type IncompatibleChangeType string
+
+// Backward incompatible changes.
+const (
+	// A served version was removed.
+	IncompatibleChangeRemovedVersion IncompatibleChangeType = "RemovedVersion"
+
+	// A property was removed.
+	IncompatibleChangeRemovedProperty IncompatibleChangeType = "RemovedProperty"
+
+	// The type of a property changed.
+	IncompatibleChangeChangedType IncompatibleChangeType = "ChangedType"
+
+	// A property that was not constrained to an enum is now, or an enum no
+	// longer allows a value it used to.
+	IncompatibleChangeTightenedEnum IncompatibleChangeType = "TightenedEnum"
+
+	// A property that was optional is now required.
+	IncompatibleChangeAddedRequired IncompatibleChangeType = "AddedRequired"
+
+	// A property that was required is now optional, so readers may no longer
+	// rely on it being present.
+	IncompatibleChangeRemovedRequired IncompatibleChangeType = "RemovedRequired"
+)
+
+// An IncompatibleChange is a backward incompatible change to the schema of a
+// version of a CustomResourceDefinition.
+type IncompatibleChange struct {
+	// Type of the change.
+	Type IncompatibleChangeType `json:"type"`
+
+	// Version that changed.
+	Version string `json:"version"`
+
+	// Path of the property that changed, for example spec.storageGB. Empty
+	// for changes to a version as a whole.
+	Path string `json:"path,omitempty"`
+}
+
+// SchemaDiff returns the backward incompatible changes between the schemas of
+// the served versions of the supplied current and desired
+// CustomResourceDefinitions, typically those derived from two revisions of a
+// CompositeResourceDefinition by ForCompositeResource or
+// ForCompositeResourceClaim. Changes are returned in a stable order.
+func SchemaDiff(current, desired *extv1.CustomResourceDefinition) ([]IncompatibleChange, error) {
+	if current == nil || desired == nil {
+		return nil, errors.New(errNilCRD)
+	}
+	if current.Spec.Names.Kind != desired.Spec.Names.Kind {
+		return nil, errors.Errorf(errFmtDiffKinds, current.Spec.Names.Kind, desired.Spec.Names.Kind)
+	}
+	if current.Spec.Group != desired.Spec.Group {
+		return nil, errors.Errorf(errFmtDiffGroups, current.Spec.Group, desired.Spec.Group)
+	}
+
+	versions := make(map[string]extv1.CustomResourceDefinitionVersion, len(desired.Spec.Versions))
+	for _, vr := range desired.Spec.Versions {
+		versions[vr.Name] = vr
+	}
+
+	var changes []IncompatibleChange
+	for _, cvr := range current.Spec.Versions {
+		if !cvr.Served {
+			continue
+		}
+		dvr, ok := versions[cvr.Name]
+		if !ok || !dvr.Served {
+			changes = append(changes, IncompatibleChange{Type: IncompatibleChangeRemovedVersion, Version: cvr.Name})
+			continue
+		}
+		if cvr.Schema == nil || cvr.Schema.OpenAPIV3Schema == nil || dvr.Schema == nil || dvr.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+		cs, ds := cvr.Schema.OpenAPIV3Schema, dvr.Schema.OpenAPIV3Schema
+		changes = append(changes, diffRequired(cvr.Name, nil, cs.Required, ds.Required)...)
+		for _, k := range sortedKeys(cs.Properties) {
+			cp := cs.Properties[k]
+			dp, ok := ds.Properties[k]
+			if !ok {
+				changes = append(changes, IncompatibleChange{Type: IncompatibleChangeRemovedProperty, Version: cvr.Name, Path: k})
+				continue
+			}
+			changes = append(changes, diffSchema(cvr.Name, field.NewPath(k), &cp, &dp)...)
+		}
+	}
+	return changes, nil
+}
+
+// diffSchema returns the backward incompatible changes between the supplied
+// current and desired schemas of the property at the supplied path.
+func diffSchema(version string, p *field.Path, current, desired *extv1.JSONSchemaProps) []IncompatibleChange {
+	change := func(t IncompatibleChangeType, p *field.Path) IncompatibleChange {
+		return IncompatibleChange{Type: t, Version: version, Path: p.String()}
+	}
+
+	if current.Type != "" && current.Type != desired.Type {
+		// Nothing below a property whose type changed is comparable.
+		return []IncompatibleChange{change(IncompatibleChangeChangedType, p)}
+	}
+
+	var changes []IncompatibleChange
+	if tightenedEnum(current.Enum, desired.Enum) {
+		changes = append(changes, change(IncompatibleChangeTightenedEnum, p))
+	}
+
+	changes = append(changes, diffRequired(version, p, current.Required, desired.Required)...)
+
+	for _, k := range sortedKeys(current.Properties) {
+		cp := current.Properties[k]
+		dp, ok := desired.Properties[k]
+		if !ok {
+			// Properties of an object that preserves unknown fields are not
+			// pruned, so removing them from the schema loses no data.
+			if desired.XPreserveUnknownFields == nil || !*desired.XPreserveUnknownFields {
+				changes = append(changes, change(IncompatibleChangeRemovedProperty, p.Child(k)))
+			}
+			continue
+		}
+		changes = append(changes, diffSchema(version, p.Child(k), &cp, &dp)...)
+	}
+
+	if current.Items != nil && current.Items.Schema != nil && desired.Items != nil && desired.Items.Schema != nil {
+		changes = append(changes, diffSchema(version, p.Key("*"), current.Items.Schema, desired.Items.Schema)...)
+	}
+	if current.AdditionalProperties != nil && current.AdditionalProperties.Schema != nil && desired.AdditionalProperties != nil && desired.AdditionalProperties.Schema != nil {
+		changes = append(changes, diffSchema(version, p.Child("*"), current.AdditionalProperties.Schema, desired.AdditionalProperties.Schema)...)
+	}
+
+	return changes
+}
+
+// diffRequired returns the backward incompatible changes between the supplied
+// current and desired required properties of the object at the supplied path.
+// A nil path is the root of the schema.
+func diffRequired(version string, p *field.Path, current, desired []string) []IncompatibleChange {
+	creq, dreq := map[string]bool{}, map[string]bool{}
+	for _, k := range current {
+		creq[k] = true
+	}
+	for _, k := range desired {
+		dreq[k] = true
+	}
+
+	var changes []IncompatibleChange
+	for _, k := range desired {
+		if !creq[k] {
+			changes = append(changes, IncompatibleChange{Type: IncompatibleChangeAddedRequired, Version: version, Path: p.Child(k).String()})
+		}
+	}
+	for _, k := range current {
+		if !dreq[k] {
+			changes = append(changes, IncompatibleChange{Type: IncompatibleChangeRemovedRequired, Version: version, Path: p.Child(k).String()})
+		}
+	}
+	return changes
+}
+
+// tightenedEnum returns true if the desired enum disallows a value the current
+// enum allowed. An empty enum allows any value.
+func tightenedEnum(current, desired []extv1.JSON) bool {
+	if len(desired) == 0 {
+		return false
+	}
+	if len(current) == 0 {
+		return true
+	}
+	allowed := make(map[string]bool, len(desired))
+	for _, e := range desired {
+		allowed[string(e.Raw)] = true
+	}
+	for _, e := range current {
+		if !allowed[string(e.Raw)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xcrd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/crossplane/apis/apiextensions/v1beta1"
+)
+
+func TestSchemaDiff(t *testing.T) {
+	current := `{"properties":{"spec":{"required":["storageGB"],"properties":{"engineVersion":{"type":"string","enum":["5.6","5.7"]},"storageGB":{"type":"integer"},"region":{"type":"string"}}}}}`
+
+	render := func(t *testing.T, schema string, m ...xrdModifier) *extv1.CustomResourceDefinition {
+		t.Helper()
+		crd, err := ForCompositeResource(xrd(append([]xrdModifier{withSchema(schema)}, m...)...))
+		if err != nil {
+			t.Fatalf("ForCompositeResource(...): %s", err)
+		}
+		return crd
+	}
+
+	type want struct {
+		changes []IncompatibleChange
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		desired string
+		m       []xrdModifier
+		crd     func(crd *extv1.CustomResourceDefinition)
+		want    want
+	}{
+		"Unchanged": {
+			reason:  "An unchanged schema should have no incompatible changes.",
+			desired: current,
+		},
+		"Compatible": {
+			reason:  "Adding an optional property and loosening an enum should be compatible.",
+			desired: `{"properties":{"spec":{"required":["storageGB"],"properties":{"engineVersion":{"type":"string","enum":["5.6","5.7","8.0"]},"storageGB":{"type":"integer"},"region":{"type":"string"},"zone":{"type":"string"}}}}}`,
+		},
+		"Incompatible": {
+			reason:  "Removing a property, tightening an enum, changing a type, and changing required-ness should be incompatible.",
+			desired: `{"properties":{"spec":{"required":["region"],"properties":{"engineVersion":{"type":"string","enum":["5.7"]},"storageGB":{"type":"string"}}}}}`,
+			want: want{
+				changes: []IncompatibleChange{
+					{Type: IncompatibleChangeAddedRequired, Version: "v1beta1", Path: "spec.region"},
+					{Type: IncompatibleChangeRemovedRequired, Version: "v1beta1", Path: "spec.storageGB"},
+					{Type: IncompatibleChangeTightenedEnum, Version: "v1beta1", Path: "spec.engineVersion"},
+					{Type: IncompatibleChangeRemovedProperty, Version: "v1beta1", Path: "spec.region"},
+					{Type: IncompatibleChangeChangedType, Version: "v1beta1", Path: "spec.storageGB"},
+				},
+			},
+		},
+		"AddedRootRequired": {
+			reason:  "Newly requiring a property at the root of the schema should be incompatible.",
+			desired: current,
+			crd: func(crd *extv1.CustomResourceDefinition) {
+				crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Required = []string{"spec"}
+			},
+			want: want{
+				changes: []IncompatibleChange{{Type: IncompatibleChangeAddedRequired, Version: "v1beta1", Path: "spec"}},
+			},
+		},
+		"AddedStatusRequired": {
+			reason:  "Newly requiring a property of the status should be incompatible.",
+			desired: `{"properties":{"spec":{"required":["storageGB"],"properties":{"engineVersion":{"type":"string","enum":["5.6","5.7"]},"storageGB":{"type":"integer"},"region":{"type":"string"}}},"status":{"required":["phase"],"properties":{"phase":{"type":"string"}}}}}`,
+			want: want{
+				changes: []IncompatibleChange{{Type: IncompatibleChangeAddedRequired, Version: "v1beta1", Path: "status.phase"}},
+			},
+		},
+		"RemovedVersion": {
+			reason:  "No longer serving a version should be incompatible.",
+			desired: current,
+			m: []xrdModifier{func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Versions[0].Served = false
+			}},
+			want: want{
+				changes: []IncompatibleChange{{Type: IncompatibleChangeRemovedVersion, Version: "v1beta1"}},
+			},
+		},
+		"DifferentKinds": {
+			reason:  "CustomResourceDefinitions of different kinds should not be diffed.",
+			desired: current,
+			m: []xrdModifier{func(d *v1beta1.CompositeResourceDefinition) {
+				d.Spec.Names.Kind = "OtherComposite"
+			}},
+			want: want{
+				err: errors.Errorf(errFmtDiffKinds, "CoolComposite", "OtherComposite"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			desired := render(t, tc.desired, tc.m...)
+			if tc.crd != nil {
+				tc.crd(desired)
+			}
+			got, err := SchemaDiff(render(t, current), desired)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nSchemaDiff(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.changes, got); diff != "" {
+				t.Errorf("\n%s\nSchemaDiff(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,1013 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xcrd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	"github.com/crossplane/crossplane/apis/apiextensions/v1beta1"
+)
+
+// An Option configures how a CustomResourceDefinition is derived from a
+// CompositeResourceDefinition.
+type Option func(o *options)
+
+const (
+	warnFmtStrippedExtensions     = "x-kubernetes extensions were removed from the schema of version %q; this may change how its fields are validated, pruned, and merged"
+	errFmtUnknownAnnotatedVersion = "annotation %q references unknown version %q"
+	errFmtUnknownColumnOverride   = "cannot override unknown printer column %q"
+)
+
+// uidPattern matches a Kubernetes UID, which is a UUID.
+const uidPattern = `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`
+
+// maxSpecFieldsLength is the maximum length of the AnnotationKeySpecFields
+// annotation, beyond which it is truncated with an ellipsis. It is well within
+// the limit the API server imposes on the total size of annotations.
+const maxSpecFieldsLength = 4096
+
+// DefaultMaxPrinterColumns is the default maximum number of printer columns a
+// version of a CustomResourceDefinition may have.
+const DefaultMaxPrinterColumns = 20
+
+// A WarningFn is called with each warning encountered while deriving a
+// CustomResourceDefinition. Warnings describe definitions that are valid, but
+// that may not behave as their author intended.
+type WarningFn func(msg string)
+
+type options struct {
+	warn                    WarningFn
+	strict                  bool
+	managementPolicies      bool
+	environment             bool
+	nestManagedFields       bool
+	maxPrinterColumns       int
+	phase                   bool
+	phases                  []string
+	forProviderPassthrough  bool
+	initProviderPassthrough bool
+	atProviderPassthrough   bool
+	tags                    bool
+	tagKeyPattern           string
+	tagValuePattern         string
+	xrdUIDAnnotation        bool
+	withoutExtensions       bool
+	xrdLabel                bool
+	maxProperties           int
+	lenientConditions       bool
+	versionAnnotations      bool
+	maxDescriptionLength    int
+	truncateDescriptions    bool
+	conversions             map[VersionConversion]bool
+	pausedUntil             bool
+	blockOwnerDeletion      *bool
+	managedMapType          string
+	withoutStatus           bool
+	dedupAnalysis           bool
+	columnOverrides         []columnOverride
+	lastReconcileTime       bool
+	conditionStatusEnum     bool
+	publishedKeys           []string
+	conditionsMapList       bool
+	statusResourceRefs      bool
+	propagatedLabels        []string
+	propagatedAnnotations   []string
+	observeOnly             bool
+	ssaFriendlyLists        bool
+	specFieldsAnnotation    bool
+	strictConditions        bool
+	enforcedDeletePolicy    bool
+}
+
+type columnOverride struct {
+	name string
+	col  extv1.CustomResourceColumnDefinition
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		warn:              func(_ string) {},
+		maxPrinterColumns: DefaultMaxPrinterColumns,
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// A VersionConversion declares that conversion between two versions of a
+// CustomResourceDefinition is supported. Conversions are bidirectional.
+type VersionConversion struct {
+	From string
+	To   string
+}
+
+// WithVersionConversions declares which conversions between versions are
+// supported. When this option is supplied it is an error for any two served
+// versions to have different schemas unless a conversion between them is
+// declared and a conversion webhook is configured.
+func WithVersionConversions(c ...VersionConversion) Option {
+	return func(o *options) {
+		o.conversions = make(map[VersionConversion]bool, len(c)*2)
+		for _, vc := range c {
+			o.conversions[vc] = true
+			o.conversions[VersionConversion{From: vc.To, To: vc.From}] = true
+		}
+	}
+}
+
+// WithWarnings calls the supplied function with any warnings encountered while
+// deriving a CustomResourceDefinition.
+func WithWarnings(fn WarningFn) Option {
+	return func(o *options) {
+		o.warn = fn
+	}
+}
+
+// WithStrictValidation enables additional checks that reject definitions that
+// would produce a valid but probably unintended CustomResourceDefinition. It
+// also constrains the uid of each Crossplane managed reference to be a UUID.
+func WithStrictValidation() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithManagementPolicies adds a spec.managementPolicies array that may be used
+// to control which operations Crossplane performs on composed resources.
+func WithManagementPolicies() Option {
+	return func(o *options) {
+		o.managementPolicies = true
+	}
+}
+
+// WithObserveOnlyManagementPolicies adds a spec.managementPolicies array that
+// must be exactly ["Observe"], so that Crossplane may only observe composed
+// resources. The constraint is expressed as an enum and item bounds, because
+// CEL validation rules are not supported by v1 CustomResourceDefinitions as of
+// this version of apiextensions.
+func WithObserveOnlyManagementPolicies() Option {
+	return func(o *options) {
+		o.observeOnly = true
+	}
+}
+
+// WithEnforcedDeletePolicy adds a spec.deletionPolicy field that defaults to
+// and only allows Delete, so that composed resources may not be orphaned. Like
+// WithObserveOnlyManagementPolicies the constraint is expressed as an enum,
+// rather than a CEL validation rule.
+func WithEnforcedDeletePolicy() Option {
+	return func(o *options) {
+		o.enforcedDeletePolicy = true
+	}
+}
+
+// WithEnvironmentField adds a spec.environment object that may be used to
+// select the EnvironmentConfigs available to a composite resource.
+func WithEnvironmentField() Option {
+	return func(o *options) {
+		o.environment = true
+	}
+}
+
+// WithManagedFieldsNested nests the spec fields that Crossplane manages, for
+// example spec.compositionRef, under spec.crossplane in order to distinguish
+// them from the fields defined by the CompositeResourceDefinition. The default
+// printer columns are updated to reflect the nested fields.
+func WithManagedFieldsNested() Option {
+	return func(o *options) {
+		o.nestManagedFields = true
+	}
+}
+
+// WithMaxPrinterColumns configures the maximum number of printer columns,
+// including the default columns, that each version of a CustomResourceDefinition
+// may have. DefaultMaxPrinterColumns is used if this option is not supplied.
+func WithMaxPrinterColumns(n int) Option {
+	return func(o *options) {
+		o.maxPrinterColumns = n
+	}
+}
+
+// WithMaxProperties configures the maximum number of properties that any object
+// in the schema of a CustomResourceDefinition may have. There is no maximum by
+// default.
+func WithMaxProperties(n int) Option {
+	return func(o *options) {
+		o.maxProperties = n
+	}
+}
+
+// WithMaxDescriptionLength configures the maximum length, in characters, of
+// any description in the schema of a CustomResourceDefinition. Descriptions
+// that exceed the maximum are truncated if truncate is true, and are otherwise
+// an error. There is no maximum by default.
+func WithMaxDescriptionLength(n int, truncate bool) Option {
+	return func(o *options) {
+		o.maxDescriptionLength = n
+		o.truncateDescriptions = truncate
+	}
+}
+
+// WithTagsField adds a spec.tags object whose values must match the supplied
+// value pattern. JSON Schema cannot constrain the keys of an object, so the key
+// pattern is documented in the description of the field, but not enforced.
+// Either pattern may be empty. Both must be valid regular expressions.
+func WithTagsField(keyPattern, valuePattern string) Option {
+	return func(o *options) {
+		o.tags = true
+		o.tagKeyPattern = keyPattern
+		o.tagValuePattern = valuePattern
+	}
+}
+
+// WithPauseUntilField adds a spec.pausedUntil timestamp that may be used to
+// pause reconciliation until a particular time, and a corresponding
+// PAUSED-UNTIL printer column that is only shown with -o wide.
+func WithPauseUntilField() Option {
+	return func(o *options) {
+		o.pausedUntil = true
+	}
+}
+
+// WithPhaseField adds a status.phase field and a corresponding PHASE printer
+// column. The field is constrained to the supplied values, if any.
+func WithPhaseField(values []string) Option {
+	return func(o *options) {
+		o.phase = true
+		o.phases = values
+	}
+}
+
+// WithPublishedKeysStatus adds a status.connectionDetails.publishedKeys array
+// that lists the connection secret keys a resource publishes. Each item of the
+// array is constrained to the supplied keys, which are typically the
+// connectionSecretKeys of a CompositeResourceDefinition.
+func WithPublishedKeysStatus(keys []string) Option {
+	return func(o *options) {
+		o.publishedKeys = keys
+	}
+}
+
+// WithLastReconcileTimeStatus adds a status.lastReconcileTime timestamp that
+// records when a resource was last reconciled, and a corresponding
+// LAST-RECONCILE printer column that is only shown with -o wide.
+func WithLastReconcileTimeStatus() Option {
+	return func(o *options) {
+		o.lastReconcileTime = true
+	}
+}
+
+// WithStatusResourceRefs adds a status.resourceRefs array that mirrors the
+// schema of spec.resourceRefs, so that controllers may publish the composed
+// resources they observe.
+func WithStatusResourceRefs() Option {
+	return func(o *options) {
+		o.statusResourceRefs = true
+	}
+}
+
+// WithForProviderPassthrough preserves any unknown fields under
+// spec.forProvider, rather than pruning them. This is useful for composite
+// resources that thinly wrap a managed resource. The rest of the spec is
+// unaffected.
+func WithForProviderPassthrough() Option {
+	return func(o *options) {
+		o.forProviderPassthrough = true
+	}
+}
+
+// WithInitProviderPassthrough preserves any unknown fields under
+// spec.initProvider, rather than pruning them. This is useful for composite
+// resources that thinly wrap a managed resource that supports initProvider.
+func WithInitProviderPassthrough() Option {
+	return func(o *options) {
+		o.initProviderPassthrough = true
+	}
+}
+
+// WithAtProviderStatusPassthrough adds a status.atProvider object that preserves
+// any unknown fields, rather than pruning them. This allows composite resources
+// to surface the observed state of the managed resources they wrap. The rest of
+// the status, including its conditions, is unaffected.
+func WithAtProviderStatusPassthrough() Option {
+	return func(o *options) {
+		o.atProviderPassthrough = true
+	}
+}
+
+// WithXRDUIDAnnotation records the UID of the CompositeResourceDefinition a
+// CustomResourceDefinition was derived from as an annotation. Unlike an owner
+// reference the annotation survives the CustomResourceDefinition being orphaned.
+func WithXRDUIDAnnotation() Option {
+	return func(o *options) {
+		o.xrdUIDAnnotation = true
+	}
+}
+
+// WithSpecFieldsAnnotation records the top-level spec fields of the storage
+// version of a CustomResourceDefinition, including those Crossplane manages,
+// as the comma separated AnnotationKeySpecFields annotation. Long lists are
+// truncated with an ellipsis.
+func WithSpecFieldsAnnotation() Option {
+	return func(o *options) {
+		o.specFieldsAnnotation = true
+	}
+}
+
+// WithoutExtensions removes all x-kubernetes vendor extensions, for example
+// x-kubernetes-preserve-unknown-fields, from the schema of the derived
+// CustomResourceDefinition. This may change how the API server validates,
+// prunes, and merges fields, and should only be used to support API servers or
+// tools that cannot handle the extensions.
+func WithoutExtensions() Option {
+	return func(o *options) {
+		o.withoutExtensions = true
+	}
+}
+
+// WithXRDLabel labels a CustomResourceDefinition with the name of the
+// CompositeResourceDefinition it was derived from, in addition to any labels
+// propagated from the CompositeResourceDefinition.
+func WithXRDLabel() Option {
+	return func(o *options) {
+		o.xrdLabel = true
+	}
+}
+
+// WithPropagatedLabels propagates only the supplied label keys from a
+// CompositeResourceDefinition to its CustomResourceDefinitions. All labels are
+// propagated by default.
+func WithPropagatedLabels(keys ...string) Option {
+	return func(o *options) {
+		o.propagatedLabels = append([]string{}, keys...)
+	}
+}
+
+// WithPropagatedAnnotations propagates only the supplied annotation keys from
+// a CompositeResourceDefinition to its CustomResourceDefinitions. All
+// annotations are propagated by default.
+func WithPropagatedAnnotations(keys ...string) Option {
+	return func(o *options) {
+		o.propagatedAnnotations = append([]string{}, keys...)
+	}
+}
+
+// WithLenientConditions does not require status conditions to have a reason.
+// This allows controllers that transiently omit a condition's reason to write
+// the status of a resource.
+func WithLenientConditions() Option {
+	return func(o *options) {
+		o.lenientConditions = true
+	}
+}
+
+// WithStrictConditions requires status conditions to have a message, which may
+// be empty, per the conventions of the Kubernetes metav1.Condition type.
+func WithStrictConditions() Option {
+	return func(o *options) {
+		o.strictConditions = true
+	}
+}
+
+// WithConditionStatusEnum constrains the status of each status condition to
+// True, False, or Unknown, per Kubernetes API conventions.
+func WithConditionStatusEnum() Option {
+	return func(o *options) {
+		o.conditionStatusEnum = true
+	}
+}
+
+// WithConditionsMapList marks the status conditions array as a map list keyed
+// by type, per Kubernetes API conventions. Server-side apply then merges
+// conditions by type, rather than replacing the entire array.
+func WithConditionsMapList() Option {
+	return func(o *options) {
+		o.conditionsMapList = true
+	}
+}
+
+// WithSSAFriendlyLists marks each array Crossplane manages with the list type
+// best suited to server-side apply. Conditions become a map list keyed by
+// type, per WithConditionsMapList, while lists of references are atomic and
+// lists of strings are sets. List types set by the schema of an XRD are
+// carried through unchanged.
+func WithSSAFriendlyLists() Option {
+	return func(o *options) {
+		o.ssaFriendlyLists = true
+		o.conditionsMapList = true
+	}
+}
+
+// WithVersionFlagsFromAnnotations derives which versions of a
+// CustomResourceDefinition are served and stored from the
+// AnnotationKeyServedVersions and AnnotationKeyStorageVersion annotations of
+// the CompositeResourceDefinition, if they are set. The served annotation is a
+// comma separated list of versions. The annotations take precedence over the
+// served and referenceable fields of each version.
+func WithVersionFlagsFromAnnotations() Option {
+	return func(o *options) {
+		o.versionAnnotations = true
+	}
+}
+
+// WithManagedMapType sets the x-kubernetes-map-type of the objects in the spec
+// that Crossplane manages, for example spec.compositionRef, to the supplied map
+// type. The map type must be either granular or atomic, and determines how the
+// objects are merged by server-side apply.
+func WithManagedMapType(t string) Option {
+	return func(o *options) {
+		o.managedMapType = t
+	}
+}
+
+// WithoutStatusSubresource disables the status subresource of each version of
+// a CustomResourceDefinition. The default printer columns that are derived
+// from the status are omitted. It is an error for any other printer column to
+// be derived from the status.
+func WithoutStatusSubresource() Option {
+	return func(o *options) {
+		o.withoutStatus = true
+	}
+}
+
+// WithDedupAnalysis warns about object schemas that are repeated within the
+// schema of a version of a CustomResourceDefinition. CustomResourceDefinitions
+// cannot reference shared schemas, so each repetition increases the size of
+// the CustomResourceDefinition.
+func WithDedupAnalysis() Option {
+	return func(o *options) {
+		o.dedupAnalysis = true
+	}
+}
+
+// WithColumnOverride replaces the named default printer column with the
+// supplied column, preserving the order of the default columns. It is an error
+// to override a column that does not exist.
+func WithColumnOverride(name string, col extv1.CustomResourceColumnDefinition) Option {
+	return func(o *options) {
+		o.columnOverrides = append(o.columnOverrides, columnOverride{name: name, col: col})
+	}
+}
+
+// WithBlockOwnerDeletion configures whether the owner reference from a
+// CustomResourceDefinition to the CompositeResourceDefinition it was derived
+// from blocks deletion of the CompositeResourceDefinition until the
+// CustomResourceDefinition is deleted. Owner deletion is blocked by default.
+func WithBlockOwnerDeletion(block bool) Option {
+	return func(o *options) {
+		o.blockOwnerDeletion = &block
+	}
+}
+
+// ownerReferences returns the owner references of a CustomResourceDefinition
+// derived from the supplied CompositeResourceDefinition.
+func (o *options) ownerReferences(xrd *v1beta1.CompositeResourceDefinition) []metav1.OwnerReference {
+	ref := meta.AsController(meta.TypedReferenceTo(xrd, v1beta1.CompositeResourceDefinitionGroupVersionKind))
+	if o.blockOwnerDeletion != nil {
+		ref.BlockOwnerDeletion = o.blockOwnerDeletion
+	}
+	return []metav1.OwnerReference{ref}
+}
+
+// labels returns the labels of a CustomResourceDefinition derived from the
+// supplied CompositeResourceDefinition.
+func (o *options) labels(xrd *v1beta1.CompositeResourceDefinition) map[string]string {
+	in := propagated(xrd.GetLabels(), o.propagatedLabels)
+	if !o.xrdLabel {
+		return in
+	}
+	l := make(map[string]string, len(in)+1)
+	for k, v := range in {
+		l[k] = v
+	}
+	l[LabelKeyXRD] = xrd.GetName()
+	return l
+}
+
+// annotations returns the annotations of a CustomResourceDefinition derived
+// from the supplied CompositeResourceDefinition.
+func (o *options) annotations(xrd *v1beta1.CompositeResourceDefinition) map[string]string {
+	in := propagated(xrd.GetAnnotations(), o.propagatedAnnotations)
+	if !o.xrdUIDAnnotation {
+		return in
+	}
+	a := make(map[string]string, len(in)+1)
+	for k, v := range in {
+		a[k] = v
+	}
+	a[AnnotationKeyXRDUID] = string(xrd.GetUID())
+	return a
+}
+
+// propagated returns a copy of the entries of the supplied map whose keys are
+// allowed. All entries are allowed if allowed is nil.
+func propagated(in map[string]string, allowed []string) map[string]string {
+	if allowed == nil {
+		if in == nil {
+			return nil
+		}
+		out := make(map[string]string, len(in))
+		for k, v := range in {
+			out[k] = v
+		}
+		return out
+	}
+	var out map[string]string
+	for _, k := range allowed {
+		v, ok := in[k]
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// specFieldsAnnotation annotates the supplied CustomResourceDefinition with
+// the top-level spec fields of its storage version, if the options require it.
+func (o *options) specFieldsAnnotation(crd *extv1.CustomResourceDefinition) {
+	if !o.specFieldsAnnotation {
+		return
+	}
+	for _, vr := range crd.Spec.Versions {
+		if !vr.Storage || vr.Schema == nil || vr.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+		fields := strings.Join(sortedKeys(vr.Schema.OpenAPIV3Schema.Properties["spec"].Properties), ",")
+		if len(fields) > maxSpecFieldsLength {
+			fields = fields[:maxSpecFieldsLength-3] + "..."
+		}
+		a := crd.GetAnnotations()
+		if a == nil {
+			a = map[string]string{}
+		}
+		a[AnnotationKeySpecFields] = fields
+		crd.SetAnnotations(a)
+	}
+}
+
+// managedSpecProps returns the supplied Crossplane managed spec props, nested
+// under a crossplane object if the options require it.
+func (o *options) managedSpecProps(props map[string]extv1.JSONSchemaProps) map[string]extv1.JSONSchemaProps {
+	if o.managedMapType != "" {
+		for k, p := range props {
+			props[k] = withMapType(p, o.managedMapType)
+		}
+	}
+	if o.strict {
+		for k, p := range props {
+			props[k] = withUIDPattern(p)
+		}
+	}
+	if !o.nestManagedFields {
+		return props
+	}
+	return map[string]extv1.JSONSchemaProps{
+		"crossplane": {Type: "object", Properties: props},
+	}
+}
+
+// reservedSpecProps returns the supplied Crossplane managed spec props, nested
+// as enabled by the options, and any optional spec props enabled by the
+// options. These are the spec props that will be injected into the schema of
+// each version, and so must not be defined by it.
+func (o *options) reservedSpecProps(managed map[string]extv1.JSONSchemaProps) map[string]extv1.JSONSchemaProps {
+	props := map[string]extv1.JSONSchemaProps{}
+	for k, v := range o.managedSpecProps(managed) {
+		props[k] = v
+	}
+	for k, v := range o.specProps() {
+		props[k] = v
+	}
+	return props
+}
+
+// printerColumns returns the supplied default printer columns, updated to
+// reflect the location of any Crossplane managed spec props, followed by any
+// optional printer columns enabled by the options, and the supplied printer
+// columns enabled by the CompositeResourceDefinition. Any column overrides are
+// then applied.
+func (o *options) printerColumns(defaults, enabled []extv1.CustomResourceColumnDefinition) ([]extv1.CustomResourceColumnDefinition, error) {
+	// Don't modify the supplied defaults.
+	cols := append([]extv1.CustomResourceColumnDefinition{}, defaults...)
+	if o.nestManagedFields {
+		for i := range cols {
+			if strings.HasPrefix(cols[i].JSONPath, ".spec.") {
+				cols[i].JSONPath = ".spec.crossplane." + strings.TrimPrefix(cols[i].JSONPath, ".spec.")
+			}
+		}
+	}
+	if o.phase {
+		cols = append(cols, PhasePrinterColumns()...)
+	}
+	if o.pausedUntil {
+		cols = append(cols, PausedUntilPrinterColumns()...)
+	}
+	if o.lastReconcileTime {
+		cols = append(cols, LastReconcileTimePrinterColumns()...)
+	}
+	cols = append(cols, enabled...)
+	if o.withoutStatus {
+		cols = withoutStatusColumns(cols)
+	}
+	for _, co := range o.columnOverrides {
+		i := columnIndex(cols, co.name)
+		if i < 0 {
+			return nil, errors.Errorf(errFmtUnknownColumnOverride, co.name)
+		}
+		cols[i] = co.col
+	}
+	return cols, nil
+}
+
+// columnIndex returns the index of the named column, or -1 if there is no
+// such column.
+func columnIndex(cols []extv1.CustomResourceColumnDefinition, name string) int {
+	for i := range cols {
+		if cols[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// specProps returns any optional spec props enabled by the options.
+func (o *options) specProps() map[string]extv1.JSONSchemaProps {
+	props := map[string]extv1.JSONSchemaProps{}
+	if o.managementPolicies {
+		for k, v := range ManagementPoliciesSpecProps() {
+			props[k] = v
+		}
+	}
+	if o.observeOnly {
+		for k, v := range ObserveOnlyManagementPoliciesSpecProps() {
+			props[k] = v
+		}
+	}
+	if o.enforcedDeletePolicy {
+		for k, v := range EnforcedDeletePolicySpecProps() {
+			props[k] = v
+		}
+	}
+	if o.environment {
+		for k, v := range EnvironmentSpecProps() {
+			props[k] = v
+		}
+	}
+	if o.tags {
+		for k, v := range TagsSpecProps(o.tagKeyPattern, o.tagValuePattern) {
+			props[k] = v
+		}
+	}
+	if o.pausedUntil {
+		for k, v := range PausedUntilSpecProps() {
+			props[k] = v
+		}
+	}
+	return props
+}
+
+// statusProps returns any optional status props enabled by the options.
+func (o *options) statusProps() map[string]extv1.JSONSchemaProps {
+	props := map[string]extv1.JSONSchemaProps{}
+	if o.phase {
+		for k, v := range PhaseStatusProps(o.phases) {
+			props[k] = v
+		}
+	}
+	if o.lastReconcileTime {
+		for k, v := range LastReconcileTimeStatusProps() {
+			props[k] = v
+		}
+	}
+	if o.statusResourceRefs {
+		for k, v := range StatusResourceRefsProps() {
+			if o.strict {
+				v = withUIDPattern(v)
+			}
+			props[k] = v
+		}
+	}
+	if o.publishedKeys != nil {
+		// Published keys share status.connectionDetails with the time at
+		// which connection details were last published.
+		base := CompositeResourceStatusProps()
+		for k, v := range PublishedKeysStatusProps(o.publishedKeys) {
+			if cur, ok := base[k]; ok {
+				for pk, pv := range v.Properties {
+					cur.Properties[pk] = pv
+				}
+				v = cur
+			}
+			props[k] = v
+		}
+	}
+	return props
+}
+
+// transform the schema of the supplied version, into which all spec and status
+// props have already been merged.
+func (o *options) transform(vr *extv1.CustomResourceDefinitionVersion) {
+	s := vr.Schema.OpenAPIV3Schema
+	if o.forProviderPassthrough {
+		preserveUnknownFields(s.Properties["spec"].Properties, "forProvider")
+	}
+	if o.initProviderPassthrough {
+		preserveUnknownFields(s.Properties["spec"].Properties, "initProvider")
+	}
+	if o.atProviderPassthrough {
+		preserveUnknownFields(s.Properties["status"].Properties, "atProvider")
+	}
+	if o.lenientConditions {
+		if c := s.Properties["status"].Properties["conditions"]; c.Items != nil && c.Items.Schema != nil {
+			c.Items.Schema.Required = without(c.Items.Schema.Required, "reason")
+		}
+	}
+	if o.strictConditions {
+		if c := s.Properties["status"].Properties["conditions"]; c.Items != nil && c.Items.Schema != nil {
+			required := append(without(c.Items.Schema.Required, "message"), "message")
+			sort.Strings(required)
+			c.Items.Schema.Required = required
+		}
+	}
+	if o.conditionsMapList {
+		if c := s.Properties["status"].Properties["conditions"]; c.Type == "array" {
+			listType := "map"
+			c.XListType = &listType
+			c.XListMapKeys = []string{"type"}
+			s.Properties["status"].Properties["conditions"] = c
+		}
+	}
+	if o.ssaFriendlyLists {
+		for _, l := range ssaListTypes {
+			path := strings.Split(l.path, ".")
+			if l.managed && o.nestManagedFields {
+				path = append([]string{path[0], "crossplane"}, path[1:]...)
+			}
+			*s = withListType(*s, path, l.listType)
+		}
+	}
+	if o.conditionStatusEnum {
+		if c := s.Properties["status"].Properties["conditions"]; c.Items != nil && c.Items.Schema != nil {
+			c.Items.Schema.Properties["status"] = extv1.JSONSchemaProps{
+				Type: "string",
+				Enum: []extv1.JSON{
+					{Raw: []byte(`"True"`)},
+					{Raw: []byte(`"False"`)},
+					{Raw: []byte(`"Unknown"`)},
+				},
+			}
+		}
+	}
+	if o.maxDescriptionLength > 0 && o.truncateDescriptions {
+		truncateDescriptions(s, o.maxDescriptionLength)
+	}
+	if o.withoutStatus {
+		vr.Subresources.Status = nil
+		if vr.Subresources.Scale == nil {
+			vr.Subresources = nil
+		}
+	}
+	if o.withoutExtensions && stripExtensions(s) {
+		o.warnf(warnFmtStrippedExtensions, vr.Name)
+	}
+}
+
+// versionFlags overrides the served and storage flags of the versions of the
+// supplied CustomResourceDefinition per the annotations of the supplied
+// CompositeResourceDefinition, if the options require it.
+func (o *options) versionFlags(xrd *v1beta1.CompositeResourceDefinition, crd *extv1.CustomResourceDefinition) error {
+	if !o.versionAnnotations {
+		return nil
+	}
+
+	exists := map[string]bool{}
+	for _, vr := range crd.Spec.Versions {
+		exists[vr.Name] = true
+	}
+
+	if a, ok := xrd.GetAnnotations()[AnnotationKeyServedVersions]; ok {
+		served := map[string]bool{}
+		for _, v := range strings.Split(a, ",") {
+			v = strings.TrimSpace(v)
+			if !exists[v] {
+				return errors.Errorf(errFmtUnknownAnnotatedVersion, AnnotationKeyServedVersions, v)
+			}
+			served[v] = true
+		}
+		for i := range crd.Spec.Versions {
+			crd.Spec.Versions[i].Served = served[crd.Spec.Versions[i].Name]
+		}
+	}
+
+	if v, ok := xrd.GetAnnotations()[AnnotationKeyStorageVersion]; ok {
+		v = strings.TrimSpace(v)
+		if !exists[v] {
+			return errors.Errorf(errFmtUnknownAnnotatedVersion, AnnotationKeyStorageVersion, v)
+		}
+		for i := range crd.Spec.Versions {
+			crd.Spec.Versions[i].Storage = crd.Spec.Versions[i].Name == v
+		}
+	}
+
+	return nil
+}
+
+// withoutStatusColumns returns the supplied printer columns, less any that are
+// derived from the status.
+func withoutStatusColumns(cols []extv1.CustomResourceColumnDefinition) []extv1.CustomResourceColumnDefinition {
+	out := make([]extv1.CustomResourceColumnDefinition, 0, len(cols))
+	for _, c := range cols {
+		if !isStatusPath(c.JSONPath) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// isStatusPath returns true if the supplied JSONPath refers to the status.
+func isStatusPath(path string) bool {
+	return path == ".status" || strings.HasPrefix(path, ".status.") || strings.HasPrefix(path, ".status[")
+}
+
+// ssaListTypes are the list types of the arrays Crossplane manages, per
+// WithSSAFriendlyLists.
+var ssaListTypes = []struct {
+	path     string
+	listType string
+
+	// Managed arrays are nested under spec.crossplane when managed fields are
+	// nested.
+	managed bool
+}{
+	{path: "spec.resourceRefs", listType: "atomic", managed: true},
+	{path: "spec.managementPolicies", listType: "set"},
+	{path: "spec.environment.environmentConfigs", listType: "atomic"},
+	{path: "status.resourceRefs", listType: "atomic"},
+	{path: "status.connectionDetails.publishedKeys", listType: "set"},
+}
+
+// withListType returns the supplied prop with the array at the supplied path,
+// if any, marked with the supplied list type. Arrays that already have a list
+// type are unchanged.
+func withListType(p extv1.JSONSchemaProps, path []string, t string) extv1.JSONSchemaProps {
+	if len(path) == 0 {
+		if p.Type == "array" && p.XListType == nil {
+			p.XListType = &t
+		}
+		return p
+	}
+	child, ok := p.Properties[path[0]]
+	if !ok {
+		return p
+	}
+	p.Properties[path[0]] = withListType(child, path[1:], t)
+	return p
+}
+
+// withMapType returns the supplied prop with the supplied map type if it is an
+// object, or an array of objects.
+func withMapType(p extv1.JSONSchemaProps, t string) extv1.JSONSchemaProps {
+	switch {
+	case p.Type == "object":
+		p.XMapType = &t
+	case p.Type == "array" && p.Items != nil && p.Items.Schema != nil && p.Items.Schema.Type == "object":
+		items := *p.Items.Schema
+		items.XMapType = &t
+		p.Items = &extv1.JSONSchemaPropsOrArray{Schema: &items}
+	}
+	return p
+}
+
+// withUIDPattern returns the supplied prop with each of its string uid props,
+// at any depth, constrained to the UUID pattern.
+func withUIDPattern(p extv1.JSONSchemaProps) extv1.JSONSchemaProps {
+	if p.Properties != nil {
+		props := make(map[string]extv1.JSONSchemaProps, len(p.Properties))
+		for k, v := range p.Properties {
+			v = withUIDPattern(v)
+			if k == "uid" && v.Type == "string" {
+				v.Pattern = uidPattern
+			}
+			props[k] = v
+		}
+		p.Properties = props
+	}
+	if p.Items != nil && p.Items.Schema != nil {
+		items := withUIDPattern(*p.Items.Schema)
+		p.Items = &extv1.JSONSchemaPropsOrArray{Schema: &items}
+	}
+	return p
+}
+
+// preserveUnknownFields marks the named object props as preserving unknown
+// fields, adding them if necessary.
+func preserveUnknownFields(props map[string]extv1.JSONSchemaProps, names ...string) {
+	for _, n := range names {
+		preserve := true
+		p := props[n]
+		p.Type = "object"
+		p.XPreserveUnknownFields = &preserve
+		props[n] = p
+	}
+}
+
+// without returns the supplied strings, less the named string.
+func without(in []string, name string) []string {
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if s != name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// truncateDescriptions recursively truncates all descriptions in the supplied
+// schema to at most n characters.
+func truncateDescriptions(s *extv1.JSONSchemaProps, n int) {
+	if r := []rune(s.Description); len(r) > n {
+		s.Description = string(r[:n])
+	}
+	for k, p := range s.Properties {
+		p := p
+		truncateDescriptions(&p, n)
+		s.Properties[k] = p
+	}
+	if s.Items != nil && s.Items.Schema != nil {
+		truncateDescriptions(s.Items.Schema, n)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		truncateDescriptions(s.AdditionalProperties.Schema, n)
+	}
+}
+
+// stripExtensions recursively removes all x-kubernetes vendor extensions from
+// the supplied schema. It returns true if any extensions were removed.
+func stripExtensions(s *extv1.JSONSchemaProps) bool {
+	stripped := s.XPreserveUnknownFields != nil || s.XEmbeddedResource || s.XIntOrString ||
+		s.XListMapKeys != nil || s.XListType != nil || s.XMapType != nil
+
+	s.XPreserveUnknownFields = nil
+	s.XEmbeddedResource = false
+	s.XIntOrString = false
+	s.XListMapKeys = nil
+	s.XListType = nil
+	s.XMapType = nil
+
+	for k, p := range s.Properties {
+		p := p
+		stripped = stripExtensions(&p) || stripped
+		s.Properties[k] = p
+	}
+	if s.Items != nil {
+		if s.Items.Schema != nil {
+			stripped = stripExtensions(s.Items.Schema) || stripped
+		}
+		for i := range s.Items.JSONSchemas {
+			stripped = stripExtensions(&s.Items.JSONSchemas[i]) || stripped
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		stripped = stripExtensions(s.AdditionalProperties.Schema) || stripped
+	}
+	for _, junctor := range [][]extv1.JSONSchemaProps{s.AllOf, s.AnyOf, s.OneOf} {
+		for i := range junctor {
+			stripped = stripExtensions(&junctor[i]) || stripped
+		}
+	}
+	if s.Not != nil {
+		stripped = stripExtensions(s.Not) || stripped
+	}
+	return stripped
+}
+
+func (o *options) warnf(format string, a ...interface{}) {
+	o.warn(fmt.Sprintf(format, a...))
+}
@@ -16,7 +16,12 @@ limitations under the License.
 
 package xcrd
 
-import extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+import (
+	"encoding/json"
+	"fmt"
+
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
 
 // TODO(negz): Add descriptions to schema fields.
 
@@ -75,10 +80,22 @@ func CompositeResourceSpecProps() map[string]extv1.JSONSchemaProps {
 			Type:     "object",
 			Required: []string{"apiVersion", "kind", "namespace", "name"},
 			Properties: map[string]extv1.JSONSchemaProps{
-				"apiVersion": {Type: "string"},
-				"kind":       {Type: "string"},
-				"namespace":  {Type: "string"},
-				"name":       {Type: "string"},
+				"apiVersion": {
+					Type:        "string",
+					Description: "APIVersion of the claim bound to this composite resource. Managed by Crossplane.",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "Kind of the claim bound to this composite resource. Managed by Crossplane.",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "Namespace of the claim bound to this composite resource. Managed by Crossplane.",
+				},
+				"name": {
+					Type:        "string",
+					Description: "Name of the claim bound to this composite resource. Managed by Crossplane.",
+				},
 			},
 		},
 		"resourceRefs": {
@@ -107,6 +124,17 @@ func CompositeResourceSpecProps() map[string]extv1.JSONSchemaProps {
 	}
 }
 
+// NamespacedCompositeResourceSpecProps is a partial OpenAPIV3Schema for the
+// spec fields that Crossplane expects to be present for all defined namespaced
+// infrastructure resources. Namespaced resources cannot be claimed, so these
+// omit the claim reference. The connection secret reference keeps its
+// namespace, which Crossplane reads when publishing connection details.
+func NamespacedCompositeResourceSpecProps() map[string]extv1.JSONSchemaProps {
+	props := CompositeResourceSpecProps()
+	delete(props, "claimRef")
+	return props
+}
+
 // CompositeResourceClaimSpecProps is a partial OpenAPIV3Schema for the spec
 // fields that Crossplane expects to be present for all published infrastructure
 // resources.
@@ -141,6 +169,8 @@ func CompositeResourceClaimSpecProps() map[string]extv1.JSONSchemaProps {
 				"name":       {Type: "string"},
 			},
 		},
+		// A claim's connection secret is always written to the claim's
+		// namespace, so we must not require a namespace here.
 		"writeConnectionSecretToRef": {
 			Type:     "object",
 			Required: []string{"name"},
@@ -151,6 +181,249 @@ func CompositeResourceClaimSpecProps() map[string]extv1.JSONSchemaProps {
 	}
 }
 
+// A ReservedSpecFieldsOption configures which spec fields ReservedSpecFields
+// returns.
+type ReservedSpecFieldsOption func(o *reservedSpecFieldsOptions)
+
+type reservedSpecFieldsOptions struct {
+	claim bool
+}
+
+// WithClaimSpecFields includes the spec fields Crossplane reserves for
+// composite resource claims.
+func WithClaimSpecFields() ReservedSpecFieldsOption {
+	return func(o *reservedSpecFieldsOptions) {
+		o.claim = true
+	}
+}
+
+// ReservedSpecFields returns the spec fields Crossplane reserves for composite
+// resources, keyed by name. A CompositeResourceDefinition may not define these
+// fields in its schema. Where a composite resource and its claim reserve the
+// same field, the composite resource's schema is returned.
+func ReservedSpecFields(opts ...ReservedSpecFieldsOption) map[string]extv1.JSONSchemaProps {
+	o := &reservedSpecFieldsOptions{}
+	for _, fn := range opts {
+		fn(o)
+	}
+	props := CompositeResourceSpecProps()
+	if !o.claim {
+		return props
+	}
+	for k, v := range CompositeResourceClaimSpecProps() {
+		if _, ok := props[k]; !ok {
+			props[k] = v
+		}
+	}
+	return props
+}
+
+// ManagementPoliciesSpecProps is a partial OpenAPIV3Schema for the spec fields
+// that control which operations Crossplane may perform on the resources that
+// compose an infrastructure resource.
+func ManagementPoliciesSpecProps() map[string]extv1.JSONSchemaProps {
+	return map[string]extv1.JSONSchemaProps{
+		"managementPolicies": {
+			Type: "array",
+			Items: &extv1.JSONSchemaPropsOrArray{
+				Schema: &extv1.JSONSchemaProps{
+					Type: "string",
+					Enum: []extv1.JSON{
+						{Raw: []byte(`"Observe"`)},
+						{Raw: []byte(`"Create"`)},
+						{Raw: []byte(`"Update"`)},
+						{Raw: []byte(`"Delete"`)},
+						{Raw: []byte(`"LateInitialize"`)},
+						{Raw: []byte(`"*"`)},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ObserveOnlyManagementPoliciesSpecProps is a partial OpenAPIV3Schema for a
+// managementPolicies spec field that only allows Crossplane to observe the
+// resources that compose an infrastructure resource.
+func ObserveOnlyManagementPoliciesSpecProps() map[string]extv1.JSONSchemaProps {
+	one := int64(1)
+	return map[string]extv1.JSONSchemaProps{
+		"managementPolicies": {
+			Type:     "array",
+			MinItems: &one,
+			MaxItems: &one,
+			Default:  &extv1.JSON{Raw: []byte(`["Observe"]`)},
+			Items: &extv1.JSONSchemaPropsOrArray{
+				Schema: &extv1.JSONSchemaProps{
+					Type: "string",
+					Enum: []extv1.JSON{{Raw: []byte(`"Observe"`)}},
+				},
+			},
+		},
+	}
+}
+
+// EnforcedDeletePolicySpecProps is a partial OpenAPIV3Schema for a
+// deletionPolicy spec field that requires the resources that compose an
+// infrastructure resource be deleted along with it, rather than orphaned.
+func EnforcedDeletePolicySpecProps() map[string]extv1.JSONSchemaProps {
+	return map[string]extv1.JSONSchemaProps{
+		"deletionPolicy": {
+			Description: "DeletionPolicy specifies what will happen to composed resources when this resource is deleted. Only Delete is allowed.",
+			Type:        "string",
+			Default:     &extv1.JSON{Raw: []byte(`"Delete"`)},
+			Enum:        []extv1.JSON{{Raw: []byte(`"Delete"`)}},
+		},
+	}
+}
+
+// EnvironmentSpecProps is a partial OpenAPIV3Schema for the spec fields that
+// select the EnvironmentConfigs available to an infrastructure resource.
+func EnvironmentSpecProps() map[string]extv1.JSONSchemaProps {
+	return map[string]extv1.JSONSchemaProps{
+		"environment": {
+			Type: "object",
+			Properties: map[string]extv1.JSONSchemaProps{
+				"environmentConfigs": {
+					Type: "array",
+					Items: &extv1.JSONSchemaPropsOrArray{
+						Schema: &extv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"ref": {
+									Type:     "object",
+									Required: []string{"name"},
+									Properties: map[string]extv1.JSONSchemaProps{
+										"name": {Type: "string"},
+									},
+								},
+								"selector": {
+									Type:     "object",
+									Required: []string{"matchLabels"},
+									Properties: map[string]extv1.JSONSchemaProps{
+										"matchLabels": {
+											Type: "object",
+											AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+												Allows: true,
+												Schema: &extv1.JSONSchemaProps{Type: "string"},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// LastReconcileTimeStatusProps is a partial OpenAPIV3Schema for a status field
+// that records when an infrastructure resource was last reconciled.
+func LastReconcileTimeStatusProps() map[string]extv1.JSONSchemaProps {
+	return map[string]extv1.JSONSchemaProps{
+		"lastReconcileTime": {
+			Description: "LastReconcileTime is the time at which the resource was last reconciled.",
+			Type:        "string",
+			Format:      "date-time",
+		},
+	}
+}
+
+// StatusResourceRefsProps is a partial OpenAPIV3Schema for a status field that
+// records the composed resources observed by a controller. Its items share the
+// schema of spec.resourceRefs.
+func StatusResourceRefsProps() map[string]extv1.JSONSchemaProps {
+	refs := CompositeResourceSpecProps()["resourceRefs"]
+	refs.Description = "ResourceRefs are the composed resources observed by Crossplane. Managed by Crossplane."
+	return map[string]extv1.JSONSchemaProps{"resourceRefs": refs}
+}
+
+// LastReconcileTimePrinterColumns returns the printer columns that correspond
+// to LastReconcileTimeStatusProps.
+func LastReconcileTimePrinterColumns() []extv1.CustomResourceColumnDefinition {
+	return []extv1.CustomResourceColumnDefinition{
+		{
+			Name:     "LAST-RECONCILE",
+			Type:     "date",
+			JSONPath: ".status.lastReconcileTime",
+			Priority: 1,
+		},
+	}
+}
+
+// PausedUntilSpecProps is a partial OpenAPIV3Schema for a spec field that
+// specifies the time until which reconciliation of an infrastructure resource
+// is paused.
+func PausedUntilSpecProps() map[string]extv1.JSONSchemaProps {
+	return map[string]extv1.JSONSchemaProps{
+		"pausedUntil": {
+			Description: "PausedUntil is the RFC 3339 time until which reconciliation of the resource is paused.",
+			Type:        "string",
+			Format:      "date-time",
+		},
+	}
+}
+
+// PausedUntilPrinterColumns returns the printer columns that correspond to
+// PausedUntilSpecProps.
+func PausedUntilPrinterColumns() []extv1.CustomResourceColumnDefinition {
+	return []extv1.CustomResourceColumnDefinition{
+		{
+			Name:     "PAUSED-UNTIL",
+			Type:     "string",
+			Format:   "date-time",
+			JSONPath: ".spec.pausedUntil",
+			Priority: 1,
+		},
+	}
+}
+
+// PausedReconcileSpecProps is a partial OpenAPIV3Schema for a spec field that
+// specifies whether reconciliation of an infrastructure resource is paused.
+func PausedReconcileSpecProps() map[string]extv1.JSONSchemaProps {
+	return map[string]extv1.JSONSchemaProps{
+		"pausedReconcile": {
+			Description: "PausedReconcile specifies whether reconciliation of the resource is paused.",
+			Type:        "boolean",
+		},
+	}
+}
+
+// PausedReconcilePrinterColumns returns the printer columns that correspond to
+// PausedReconcileSpecProps.
+func PausedReconcilePrinterColumns() []extv1.CustomResourceColumnDefinition {
+	return []extv1.CustomResourceColumnDefinition{
+		{
+			Name:     "PAUSED",
+			Type:     "boolean",
+			JSONPath: ".spec.pausedReconcile",
+		},
+	}
+}
+
+// TagsSpecProps is a partial OpenAPIV3Schema for a spec field that specifies
+// the tags of an infrastructure resource. Tag values must match the supplied
+// value pattern, if any. JSON Schema cannot constrain the keys of an object, so
+// the key pattern, if any, is only documented in the field's description.
+func TagsSpecProps(keyPattern, valuePattern string) map[string]extv1.JSONSchemaProps {
+	d := "Tags to apply to the resource."
+	if keyPattern != "" {
+		d += fmt.Sprintf(" Tag keys must match the pattern %s.", keyPattern)
+	}
+	return map[string]extv1.JSONSchemaProps{
+		"tags": {
+			Description: d,
+			Type:        "object",
+			AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+				Allows: true,
+				Schema: &extv1.JSONSchemaProps{Type: "string", Pattern: valuePattern},
+			},
+		},
+	}
+}
+
 // CompositeResourceStatusProps is a partial OpenAPIV3Schema for the status
 // fields that Crossplane expects to be present for all defined or published
 // infrastructure resources.
@@ -182,6 +455,53 @@ func CompositeResourceStatusProps() map[string]extv1.JSONSchemaProps {
 	}
 }
 
+// PublishedKeysStatusProps is a partial OpenAPIV3Schema for a status field
+// that lists the connection secret keys an infrastructure resource publishes.
+// Each item is constrained to the supplied keys.
+func PublishedKeysStatusProps(keys []string) map[string]extv1.JSONSchemaProps {
+	items := extv1.JSONSchemaProps{Type: "string"}
+	for _, k := range keys {
+		raw, _ := json.Marshal(k) // Marshalling a string cannot fail.
+		items.Enum = append(items.Enum, extv1.JSON{Raw: raw})
+	}
+	return map[string]extv1.JSONSchemaProps{
+		"connectionDetails": {
+			Type: "object",
+			Properties: map[string]extv1.JSONSchemaProps{
+				"publishedKeys": {
+					Description: "PublishedKeys are the connection secret keys this resource publishes.",
+					Type:        "array",
+					Items:       &extv1.JSONSchemaPropsOrArray{Schema: &items},
+				},
+			},
+		},
+	}
+}
+
+// PhaseStatusProps is a partial OpenAPIV3Schema for a status field that
+// summarises the phase of an infrastructure resource. The field is constrained
+// to the supplied phases, if any.
+func PhaseStatusProps(phases []string) map[string]extv1.JSONSchemaProps {
+	p := extv1.JSONSchemaProps{Type: "string"}
+	for _, ph := range phases {
+		raw, _ := json.Marshal(ph) // Marshalling a string cannot fail.
+		p.Enum = append(p.Enum, extv1.JSON{Raw: raw})
+	}
+	return map[string]extv1.JSONSchemaProps{"phase": p}
+}
+
+// PhasePrinterColumns returns the printer columns that correspond to
+// PhaseStatusProps.
+func PhasePrinterColumns() []extv1.CustomResourceColumnDefinition {
+	return []extv1.CustomResourceColumnDefinition{
+		{
+			Name:     "PHASE",
+			Type:     "string",
+			JSONPath: ".status.phase",
+		},
+	}
+}
+
 // CompositeResourcePrinterColumns returns the set of default printer columns
 // that should exist in all generated composite resource CRDs.
 func CompositeResourcePrinterColumns() []extv1.CustomResourceColumnDefinition {
@@ -199,6 +519,18 @@ func CompositeResourcePrinterColumns() []extv1.CustomResourceColumnDefinition {
 	}
 }
 
+// AgePrinterColumns returns the printer columns that show the age of a
+// resource, per Kubernetes convention.
+func AgePrinterColumns() []extv1.CustomResourceColumnDefinition {
+	return []extv1.CustomResourceColumnDefinition{
+		{
+			Name:     "AGE",
+			Type:     "date",
+			JSONPath: ".metadata.creationTimestamp",
+		},
+	}
+}
+
 // CompositeResourceClaimPrinterColumns returns the set of default printer
 // columns that should exist in all generated composite resource claim CRDs.
 func CompositeResourceClaimPrinterColumns() []extv1.CustomResourceColumnDefinition {
@@ -0,0 +1,79 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xcrd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestWriteConnectionSecretToRefRequired(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		props  func() map[string]extv1.JSONSchemaProps
+		want   []string
+	}{
+		"Composite": {
+			reason: "A composite resource may write its connection secret to any namespace, so the namespace must be specified.",
+			props:  CompositeResourceSpecProps,
+			want:   []string{"name", "namespace"},
+		},
+		"Claim": {
+			reason: "A claim's connection secret is always written to the claim's namespace, so only the name may be required.",
+			props:  CompositeResourceClaimSpecProps,
+			want:   []string{"name"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.props()["writeConnectionSecretToRef"].Required
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nwriteConnectionSecretToRef.required: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReservedSpecFields(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []ReservedSpecFieldsOption
+		want   []string
+	}{
+		"Composite": {
+			reason: "Only the fields reserved for composite resources should be returned by default.",
+			want:   []string{"claimRef", "compositionRef", "compositionSelector", "resourceRefs", "writeConnectionSecretToRef"},
+		},
+		"WithClaim": {
+			reason: "The fields reserved for claims should also be returned when requested.",
+			opts:   []ReservedSpecFieldsOption{WithClaimSpecFields()},
+			want:   []string{"claimRef", "compositionRef", "compositionSelector", "resourceRef", "resourceRefs", "writeConnectionSecretToRef"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := sortedKeys(ReservedSpecFields(tc.opts...))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nReservedSpecFields(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,840 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xcrd
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/crossplane/crossplane/apis/apiextensions/v1beta1"
+)
+
+const (
+	errInvalidComposite    = "invalid composite resource"
+	errInvalidClaim        = "invalid composite resource claim"
+	errFmtInvalidVersion   = "invalid version %q"
+	errEmptySpec           = "spec schema has no properties; add properties to the spec of the composite resource schema"
+	errFmtTooManyColumns   = "%d printer columns exceeds the maximum of %d; consider giving less important columns a priority greater than 0 so that they are only shown with -o wide"
+	errUnknownFormat       = "unknown format"
+	errUnsupportedKeyword  = "keyword is not supported by CustomResourceDefinition schemas"
+	errRootAdditionalProps = "additionalProperties must not be set at the root of the schema, which is managed by Crossplane"
+	errRootPreserveUnknown = "the root of the schema must not preserve unknown fields; unknown top-level fields must be pruned"
+	errUnresolvedJSONPath  = "does not resolve to a field of the schema"
+	errNegativePriority    = "must be greater than or equal to 0"
+	errLowercaseColumnName = "should be uppercase, per convention"
+	errFmtInvalidTagsRegex = "invalid tags %s pattern"
+	errBooleanEnum         = "boolean properties should not declare an enum"
+	errMalformedEnum       = "must be well-formed JSON"
+	errEnumType            = "must match the type of the property"
+	errFmtInvalidMapType   = "invalid map type %q; must be granular or atomic"
+	errStatusColumn        = "cannot refer to the status when the status subresource is disabled"
+	errScaleReplicasPath   = "must resolve to an integer field of the schema"
+	errScaleSelectorPath   = "must resolve to a string field of the schema"
+	errNegativeItems       = "must be greater than or equal to 0"
+	errMaxItemsLessThanMin = "must be greater than or equal to minItems"
+	errMaximumLessThanMin  = "must be greater than or equal to minimum"
+	errExclusiveBoundEqual = "must be greater than minimum when either bound is exclusive"
+	errFmtVersionName      = "version name %q is not a valid Kubernetes API version; it must be v<major>, optionally followed by alpha<minor> or beta<minor>, for example v1alpha1"
+	errFmtStorageVersions  = "exactly one version must be referenceable, and thus stored; found %d: %v"
+
+	errFmtUnknownConversionVersion = "declared conversion references unknown version %q"
+	errFmtUndeclaredConversion     = "served versions %q and %q have different schemas but no conversion between them is declared"
+	errFmtNoConversionWebhook      = "served versions %q and %q have different schemas but no conversion webhook is configured"
+
+	warnFmtUnconventionalSingular = "singular name %q is not the lowercase of kind %q"
+	warnFmtUnconventionalPlural   = "plural name %q is not the plural of singular name %q; expected %q"
+	warnFmtDivergentSchemas       = "served versions %q and %q have different schemas but no conversion webhook is configured; fields may be lost when converting between them"
+	warnFmtIgnoredRootAdditional  = "additionalProperties at the root of the schema of version %q is ignored; the root of the schema is managed by Crossplane"
+	warnFmtDuplicateColumnPath    = "printer columns %q and %q of version %q have the same JSONPath %q"
+	warnFmtReservedGroup          = "group %q is reserved by Kubernetes; use a group within a domain you own, for example %q"
+	warnFmtRepeatedSchema         = "version %q repeats an identical %d byte object schema %d times (%d bytes in total) at %s"
+	warnFmtSwappedSingular        = "singular name %q of the %s appears to derive from kind %q of the %s; check that their names are not swapped"
+	warnFmtPluralPrefix           = "plural name %q is a prefix of plural name %q in group %q; clients that resolve resources by prefix may confuse them"
+)
+
+// Validate the supplied CompositeResourceDefinition by deriving the
+// CustomResourceDefinitions for its composite resource and, if offered, its
+// composite resource claim. Any errors encountered are returned.
+func Validate(xrd *v1beta1.CompositeResourceDefinition, opts ...Option) []error {
+	var errs []error
+	if _, err := ForCompositeResource(xrd, opts...); err != nil {
+		errs = append(errs, errors.Wrap(err, errInvalidComposite))
+	}
+	if !xrd.OffersClaim() {
+		return errs
+	}
+	if _, err := ForCompositeResourceClaim(xrd, opts...); err != nil {
+		errs = append(errs, errors.Wrap(err, errInvalidClaim))
+	}
+	return errs
+}
+
+// ValidateBatch validates each of the supplied CompositeResourceDefinitions.
+// It returns the errors encountered validating each definition, keyed by the
+// name of the definition. Near collisions between the names of different
+// definitions are reported as warnings.
+func ValidateBatch(xrds []*v1beta1.CompositeResourceDefinition, opts ...Option) map[string][]error {
+	results := make(map[string][]error, len(xrds))
+	for _, xrd := range xrds {
+		results[xrd.GetName()] = append(results[xrd.GetName()], Validate(xrd, opts...)...)
+	}
+	newOptions(opts...).checkPluralPrefixes(xrds)
+	return results
+}
+
+// checkPluralPrefixes warns when the plural name of a composite resource or
+// claim is a prefix of another plural name in the same group, for example
+// database and databases. Such names are allowed, but are easily confused.
+func (o *options) checkPluralPrefixes(xrds []*v1beta1.CompositeResourceDefinition) {
+	plurals := map[string][]string{}
+	for _, xrd := range xrds {
+		g := xrd.Spec.Group
+		plurals[g] = append(plurals[g], xrd.Spec.Names.Plural)
+		if xrd.OffersClaim() {
+			plurals[g] = append(plurals[g], xrd.Spec.ClaimNames.Plural)
+		}
+	}
+
+	groups := make([]string, 0, len(plurals))
+	for g := range plurals {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	for _, g := range groups {
+		p := plurals[g]
+		sort.Strings(p)
+		for i := range p {
+			for j := i + 1; j < len(p); j++ {
+				if p[i] != p[j] && strings.HasPrefix(p[j], p[i]) {
+					o.warnf(warnFmtPluralPrefix, p[i], p[j], g)
+				}
+			}
+		}
+	}
+}
+
+// Column types and formats supported by Kubernetes. See
+// https://kubernetes.io/docs/tasks/extend-kubernetes/custom-resources/custom-resource-definitions/#additional-printer-columns
+var (
+	columnTypes   = []string{"boolean", "date", "integer", "number", "string"}
+	columnFormats = []string{"byte", "date", "date-time", "double", "float", "int32", "int64", "password"}
+)
+
+// Kubernetes API versions, for example v1 or v2beta1. Versions that begin with
+// kubeLikeVersionPrefix are assumed to be intended as Kubernetes API versions.
+var (
+	kubeLikeVersion       = regexp.MustCompile(`^v[1-9][0-9]*((alpha|beta)[1-9][0-9]*)?$`)
+	kubeLikeVersionPrefix = regexp.MustCompile(`^v[0-9]`)
+)
+
+// Formats are the string formats supported by Kubernetes. See
+// https://github.com/kubernetes/kube-openapi/blob/master/pkg/validation/strfmt/default.go
+var formats = map[string]bool{
+	"bsonobjectid": true,
+	"uri":          true,
+	"email":        true,
+	"hostname":     true,
+	"ipv4":         true,
+	"ipv6":         true,
+	"cidr":         true,
+	"mac":          true,
+	"uuid":         true,
+	"uuid3":        true,
+	"uuid4":        true,
+	"uuid5":        true,
+	"isbn":         true,
+	"isbn10":       true,
+	"isbn13":       true,
+	"creditcard":   true,
+	"ssn":          true,
+	"hexcolor":     true,
+	"rgbcolor":     true,
+	"byte":         true,
+	"password":     true,
+	"date":         true,
+	"duration":     true,
+	"datetime":     true,
+	"date-time":    true,
+}
+
+// validate the supplied CustomResourceDefinition, which is assumed to have
+// been rendered using the supplied options.
+func (o *options) validate(crd *extv1.CustomResourceDefinition) error {
+	if err := o.validateTagPatterns(); err != nil {
+		return err
+	}
+
+	if t := o.managedMapType; t != "" && t != "granular" && t != "atomic" {
+		return errors.Errorf(errFmtInvalidMapType, t)
+	}
+
+	if err := validateVersionNames(crd); err != nil {
+		return err
+	}
+
+	if err := validateStorageVersions(crd); err != nil {
+		return err
+	}
+
+	if o.conversions != nil {
+		if err := o.validateConversions(crd); err != nil {
+			return err
+		}
+	} else {
+		o.checkConversion(crd)
+	}
+
+	if o.dedupAnalysis {
+		for _, vr := range crd.Spec.Versions {
+			o.checkRepeatedSchemas(vr)
+		}
+	}
+
+	if o.strict {
+		o.checkGroup(crd.Spec.Group)
+		o.checkNames(crd.Spec.Names)
+		for _, vr := range crd.Spec.Versions {
+			o.checkColumnPaths(vr)
+		}
+	}
+
+	for _, vr := range crd.Spec.Versions {
+		if err := o.validateVersion(vr); err != nil {
+			return errors.Wrapf(err, errFmtInvalidVersion, vr.Name)
+		}
+	}
+
+	return nil
+}
+
+func (o *options) validateVersion(vr extv1.CustomResourceDefinitionVersion) error {
+	// Only apiVersion, kind, metadata, spec, and status may appear at the root
+	// of a resource. Anything else must be pruned by the API server.
+	if p := vr.Schema.OpenAPIV3Schema.XPreserveUnknownFields; p != nil && *p {
+		return errors.New(errRootPreserveUnknown)
+	}
+
+	if n := len(vr.AdditionalPrinterColumns); n > o.maxPrinterColumns {
+		return errors.Errorf(errFmtTooManyColumns, n, o.maxPrinterColumns)
+	}
+
+	errs := o.validatePrinterColumns(vr)
+	errs = append(errs, validateScale(vr)...)
+	errs = append(errs, o.validateDescriptionLength(nil, vr.Schema.OpenAPIV3Schema)...)
+	walkProps(vr.Schema.OpenAPIV3Schema, func(p *field.Path, s *extv1.JSONSchemaProps) {
+		errs = append(errs, validateKeywords(p, s)...)
+		errs = append(errs, o.validatePropertyCount(p, s)...)
+		errs = append(errs, o.validateDescriptionLength(p, s)...)
+		errs = append(errs, validateEnum(p, s)...)
+	})
+	if len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+
+	if !o.strict {
+		return nil
+	}
+
+	if err := validateSpecNotEmpty(vr.Schema.OpenAPIV3Schema); err != nil {
+		return err
+	}
+
+	walkProps(vr.Schema.OpenAPIV3Schema, func(p *field.Path, s *extv1.JSONSchemaProps) {
+		errs = append(errs, validateFormat(p, s)...)
+		errs = append(errs, validateBooleanEnum(p, s)...)
+		errs = append(errs, validateItemBounds(p, s)...)
+		errs = append(errs, validateNumericBounds(p, s)...)
+	})
+	return errs.ToAggregate()
+}
+
+// validateVersionNames returns an error if a version of the supplied
+// CustomResourceDefinition appears to be intended as a Kubernetes API version,
+// but is not one; for example v1alpha rather than v1alpha1. Versions that are
+// not intended as Kubernetes API versions, for example foo1, are allowed.
+func validateVersionNames(crd *extv1.CustomResourceDefinition) error {
+	for _, vr := range crd.Spec.Versions {
+		if kubeLikeVersionPrefix.MatchString(vr.Name) && !kubeLikeVersion.MatchString(vr.Name) {
+			return errors.Errorf(errFmtVersionName, vr.Name)
+		}
+	}
+	return nil
+}
+
+// validateStorageVersions returns an error unless exactly one version of the
+// supplied CustomResourceDefinition is stored.
+func validateStorageVersions(crd *extv1.CustomResourceDefinition) error {
+	stored := []string{}
+	for _, vr := range crd.Spec.Versions {
+		if vr.Storage {
+			stored = append(stored, vr.Name)
+		}
+	}
+	if len(stored) != 1 {
+		return errors.Errorf(errFmtStorageVersions, len(stored), stored)
+	}
+	return nil
+}
+
+// validateSchemaRoot checks that the root of the schema of the supplied version
+// does not set additionalProperties. The root of the schema is managed by
+// Crossplane, which adds the apiVersion, kind, metadata, spec, and status
+// properties, so additionalProperties set at the root is ignored. This is an
+// error when strict validation is enabled.
+func (o *options) validateSchemaRoot(vr v1beta1.CompositeResourceDefinitionVersion) error {
+	if vr.Schema == nil {
+		return nil
+	}
+
+	// Schemas that cannot be parsed are reported by BuildSchema.
+	s := &extv1.JSONSchemaProps{}
+	if err := json.Unmarshal(vr.Schema.OpenAPIV3Schema.Raw, s); err != nil {
+		return nil
+	}
+
+	if s.AdditionalProperties == nil {
+		return nil
+	}
+	if o.strict {
+		return errors.New(errRootAdditionalProps)
+	}
+	o.warnf(warnFmtIgnoredRootAdditional, vr.Name)
+	return nil
+}
+
+// validatePrinterColumns returns an error for each invalid printer column of
+// the supplied version. The JSONPath of each column must resolve to a field of
+// the version's schema.
+func (o *options) validatePrinterColumns(vr extv1.CustomResourceDefinitionVersion) field.ErrorList {
+	errs := field.ErrorList{}
+	names := map[string]bool{}
+	status := vr.Subresources != nil && vr.Subresources.Status != nil
+	for i, c := range vr.AdditionalPrinterColumns {
+		p := field.NewPath("additionalPrinterColumns").Index(i)
+		switch {
+		case c.Name == "":
+			errs = append(errs, field.Required(p.Child("name"), ""))
+		case names[c.Name]:
+			errs = append(errs, field.Duplicate(p.Child("name"), c.Name))
+		case o.strict && c.Name != strings.ToUpper(c.Name):
+			errs = append(errs, field.Invalid(p.Child("name"), c.Name, errLowercaseColumnName))
+		}
+		names[c.Name] = true
+		if !contains(columnTypes, c.Type) {
+			errs = append(errs, field.NotSupported(p.Child("type"), c.Type, columnTypes))
+		}
+		if c.Format != "" && !contains(columnFormats, c.Format) {
+			errs = append(errs, field.NotSupported(p.Child("format"), c.Format, columnFormats))
+		}
+		if c.Priority < 0 {
+			errs = append(errs, field.Invalid(p.Child("priority"), c.Priority, errNegativePriority))
+		}
+		if c.JSONPath == "" {
+			errs = append(errs, field.Required(p.Child("jsonPath"), ""))
+		} else if !resolveJSONPath(vr.Schema.OpenAPIV3Schema, c.JSONPath) {
+			errs = append(errs, field.Invalid(p.Child("jsonPath"), c.JSONPath, errUnresolvedJSONPath))
+		}
+		if !status && isStatusPath(c.JSONPath) {
+			errs = append(errs, field.Invalid(p.Child("jsonPath"), c.JSONPath, errStatusColumn))
+		}
+	}
+	return errs
+}
+
+// validateScale returns an error for each path of the scale subresource of the
+// supplied version, if any, that does not resolve to a field of the expected
+// type in the version's schema.
+func validateScale(vr extv1.CustomResourceDefinitionVersion) field.ErrorList {
+	if vr.Subresources == nil || vr.Subresources.Scale == nil {
+		return nil
+	}
+
+	sc := vr.Subresources.Scale
+	p := field.NewPath("subresources", "scale")
+	errs := field.ErrorList{}
+	if f := lookupField(vr.Schema.OpenAPIV3Schema, sc.SpecReplicasPath); f == nil || f.Type != "integer" {
+		errs = append(errs, field.Invalid(p.Child("specReplicasPath"), sc.SpecReplicasPath, errScaleReplicasPath))
+	}
+	if f := lookupField(vr.Schema.OpenAPIV3Schema, sc.StatusReplicasPath); f == nil || f.Type != "integer" {
+		errs = append(errs, field.Invalid(p.Child("statusReplicasPath"), sc.StatusReplicasPath, errScaleReplicasPath))
+	}
+	if sc.LabelSelectorPath == nil {
+		return errs
+	}
+	if f := lookupField(vr.Schema.OpenAPIV3Schema, *sc.LabelSelectorPath); f == nil || f.Type != "string" {
+		errs = append(errs, field.Invalid(p.Child("labelSelectorPath"), *sc.LabelSelectorPath, errScaleSelectorPath))
+	}
+	return errs
+}
+
+// lookupField returns the schema of the field of the supplied schema at the
+// supplied JSONPath, for example .spec.replicas, or nil if there is no such
+// field. Only paths without array notation are supported.
+func lookupField(s *extv1.JSONSchemaProps, path string) *extv1.JSONSchemaProps {
+	if !strings.HasPrefix(path, ".") {
+		return nil
+	}
+	for _, t := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		p, ok := s.Properties[t]
+		if !ok {
+			return nil
+		}
+		s = &p
+	}
+	return s
+}
+
+// resolveJSONPath returns true if the supplied simple JSONPath, for example
+// .status.conditions[?(@.type=='Ready')].status, resolves to a field of the
+// supplied schema. Paths that descend into objects whose fields are unknown
+// are assumed to resolve.
+func resolveJSONPath(s *extv1.JSONSchemaProps, path string) bool {
+	for _, t := range jsonPathTokens(path) {
+		if s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields {
+			return true
+		}
+		if t == "[]" {
+			if s.Items == nil || s.Items.Schema == nil {
+				return false
+			}
+			s = s.Items.Schema
+			continue
+		}
+		if p, ok := s.Properties[t]; ok {
+			s = &p
+			continue
+		}
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			s = s.AdditionalProperties.Schema
+			continue
+		}
+		return (s.Type == "object" || s.Type == "") && len(s.Properties) == 0
+	}
+	return true
+}
+
+// jsonPathTokens splits the supplied simple JSONPath into field names. Each
+// index or filter expression, for example [0] or [?(@.type=='Ready')], is
+// returned as "[]".
+func jsonPathTokens(path string) []string {
+	tokens := []string{}
+	cur := strings.Builder{}
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	depth := 0
+	for _, r := range path {
+		switch {
+		case r == '[':
+			if depth == 0 {
+				flush()
+			}
+			depth++
+		case r == ']':
+			depth--
+			if depth == 0 {
+				tokens = append(tokens, "[]")
+			}
+		case depth > 0:
+			// Indexes and filter expressions don't affect which field the
+			// path resolves to.
+		case r == '.':
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func contains(in []string, s string) bool {
+	for _, v := range in {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSpecNotEmpty returns an error if the spec of the supplied schema, into
+// which any Crossplane managed spec props have already been injected, has no
+// properties.
+func validateSpecNotEmpty(s *extv1.JSONSchemaProps) error {
+	if len(s.Properties["spec"].Properties) == 0 {
+		return errors.New(errEmptySpec)
+	}
+	return nil
+}
+
+// validateTagPatterns returns an error if the tag patterns supplied by the
+// options are not valid regular expressions.
+func (o *options) validateTagPatterns() error {
+	if !o.tags {
+		return nil
+	}
+	if _, err := regexp.Compile(o.tagKeyPattern); err != nil {
+		return errors.Wrapf(err, errFmtInvalidTagsRegex, "key")
+	}
+	if _, err := regexp.Compile(o.tagValuePattern); err != nil {
+		return errors.Wrapf(err, errFmtInvalidTagsRegex, "value")
+	}
+	return nil
+}
+
+// checkNames warns when the supplied names don't follow the conventions that
+// tools like kubectl assume.
+func (o *options) checkNames(n extv1.CustomResourceDefinitionNames) {
+	if n.Singular != "" && n.Singular != strings.ToLower(n.Kind) {
+		o.warnf(warnFmtUnconventionalSingular, n.Singular, n.Kind)
+	}
+
+	singular := n.Singular
+	if singular == "" {
+		singular = strings.ToLower(n.Kind)
+	}
+	if p := pluralize(singular); n.Plural != "" && n.Plural != p {
+		o.warnf(warnFmtUnconventionalPlural, n.Plural, singular, p)
+	}
+}
+
+// checkSwappedNames warns when the singular name of the supplied composite
+// resource or claim names appears to derive from the kind of the other, rather
+// than from its own kind.
+func (o *options) checkSwappedNames(xr, claim extv1.CustomResourceDefinitionNames) {
+	derives := func(singular, kind string) bool {
+		return strings.Contains(strings.ToLower(singular), strings.ToLower(kind))
+	}
+	if s := xr.Singular; s != "" && !derives(s, xr.Kind) && derives(s, claim.Kind) {
+		o.warnf(warnFmtSwappedSingular, s, "composite resource", claim.Kind, "claim")
+	}
+	if s := claim.Singular; s != "" && !derives(s, claim.Kind) && derives(s, xr.Kind) {
+		o.warnf(warnFmtSwappedSingular, s, "claim", xr.Kind, "composite resource")
+	}
+}
+
+// pluralize returns the plural of the supplied English noun using a simple
+// heuristic. It does not handle irregular nouns.
+func pluralize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsAny(s[len(s)-2:len(s)-1], "aeiou"):
+		return strings.TrimSuffix(s, "y") + "ies"
+	}
+	return s + "s"
+}
+
+// checkGroup warns when the supplied group is reserved by Kubernetes. The
+// built-in API groups, for example apps, have no domain, while groups within
+// the k8s.io and kubernetes.io domains require API review approval.
+func (o *options) checkGroup(g string) {
+	reserved := !strings.Contains(g, ".")
+	for _, d := range []string{"k8s.io", "kubernetes.io"} {
+		reserved = reserved || g == d || strings.HasSuffix(g, "."+d)
+	}
+	if reserved {
+		o.warnf(warnFmtReservedGroup, g, strings.Split(g, ".")[0]+".example.org")
+	}
+}
+
+// checkColumnPaths warns when printer columns of the supplied version share a
+// JSONPath, which is usually a mistake.
+func (o *options) checkColumnPaths(vr extv1.CustomResourceDefinitionVersion) {
+	seen := map[string]string{}
+	for _, c := range vr.AdditionalPrinterColumns {
+		if n, ok := seen[c.JSONPath]; ok {
+			o.warnf(warnFmtDuplicateColumnPath, n, c.Name, vr.Name, c.JSONPath)
+			continue
+		}
+		seen[c.JSONPath] = c.Name
+	}
+}
+
+// checkRepeatedSchemas warns about identical object schemas that are repeated
+// within the schema of the supplied version.
+func (o *options) checkRepeatedSchemas(vr extv1.CustomResourceDefinitionVersion) {
+	type repeated struct {
+		size  int
+		paths []string
+	}
+	seen := map[string]*repeated{}
+	order := []string{}
+	walkProps(vr.Schema.OpenAPIV3Schema, func(p *field.Path, s *extv1.JSONSchemaProps) {
+		if len(s.Properties) == 0 {
+			return
+		}
+		raw, err := json.Marshal(s)
+		if err != nil {
+			return
+		}
+		r, ok := seen[string(raw)]
+		if !ok {
+			r = &repeated{size: len(raw)}
+			seen[string(raw)] = r
+			order = append(order, string(raw))
+		}
+		r.paths = append(r.paths, p.String())
+	})
+	for _, k := range order {
+		if r := seen[k]; len(r.paths) > 1 {
+			o.warnf(warnFmtRepeatedSchema, vr.Name, r.size, len(r.paths), r.size*len(r.paths), strings.Join(r.paths, ", "))
+		}
+	}
+}
+
+// checkConversion warns when served versions have divergent schemas, but no
+// conversion webhook is configured to convert between them.
+func (o *options) checkConversion(crd *extv1.CustomResourceDefinition) {
+	if c := crd.Spec.Conversion; c != nil && c.Strategy == extv1.WebhookConverter {
+		return
+	}
+
+	served := make([]extv1.CustomResourceDefinitionVersion, 0, len(crd.Spec.Versions))
+	for _, vr := range crd.Spec.Versions {
+		if vr.Served {
+			served = append(served, vr)
+		}
+	}
+
+	for i := 1; i < len(served); i++ {
+		if !equality.Semantic.DeepEqual(served[0].Schema, served[i].Schema) {
+			o.warnf(warnFmtDivergentSchemas, served[0].Name, served[i].Name)
+		}
+	}
+}
+
+// validateConversions returns an error if any two served versions have
+// different schemas, but either no conversion between them is declared or no
+// conversion webhook is configured.
+func (o *options) validateConversions(crd *extv1.CustomResourceDefinition) error {
+	exists := map[string]bool{}
+	for _, vr := range crd.Spec.Versions {
+		exists[vr.Name] = true
+	}
+	for vc := range o.conversions {
+		for _, v := range []string{vc.From, vc.To} {
+			if !exists[v] {
+				return errors.Errorf(errFmtUnknownConversionVersion, v)
+			}
+		}
+	}
+
+	webhook := crd.Spec.Conversion != nil && crd.Spec.Conversion.Strategy == extv1.WebhookConverter
+	for i, a := range crd.Spec.Versions {
+		for _, b := range crd.Spec.Versions[i+1:] {
+			if !a.Served || !b.Served || equality.Semantic.DeepEqual(a.Schema, b.Schema) {
+				continue
+			}
+			if !o.conversions[VersionConversion{From: a.Name, To: b.Name}] {
+				return errors.Errorf(errFmtUndeclaredConversion, a.Name, b.Name)
+			}
+			if !webhook {
+				return errors.Errorf(errFmtNoConversionWebhook, a.Name, b.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateKeywords returns an error for each JSON Schema keyword used by the
+// supplied schema that CustomResourceDefinitions do not support.
+func validateKeywords(p *field.Path, s *extv1.JSONSchemaProps) field.ErrorList {
+	errs := field.ErrorList{}
+	if s.ID != "" {
+		errs = append(errs, field.Forbidden(p.Child("id"), errUnsupportedKeyword))
+	}
+	if s.Schema != "" {
+		errs = append(errs, field.Forbidden(p.Child("$schema"), errUnsupportedKeyword))
+	}
+	if s.Ref != nil {
+		errs = append(errs, field.Forbidden(p.Child("$ref"), errUnsupportedKeyword))
+	}
+	if s.Definitions != nil {
+		errs = append(errs, field.Forbidden(p.Child("definitions"), errUnsupportedKeyword))
+	}
+	if s.PatternProperties != nil {
+		errs = append(errs, field.Forbidden(p.Child("patternProperties"), errUnsupportedKeyword))
+	}
+	if s.Dependencies != nil {
+		errs = append(errs, field.Forbidden(p.Child("dependencies"), errUnsupportedKeyword))
+	}
+	if s.AdditionalItems != nil {
+		errs = append(errs, field.Forbidden(p.Child("additionalItems"), errUnsupportedKeyword))
+	}
+	return errs
+}
+
+// validatePropertyCount returns an error if the supplied schema has more
+// properties than the options allow.
+func (o *options) validatePropertyCount(p *field.Path, s *extv1.JSONSchemaProps) field.ErrorList {
+	if o.maxProperties <= 0 || len(s.Properties) <= o.maxProperties {
+		return nil
+	}
+	return field.ErrorList{field.TooMany(p.Child("properties"), len(s.Properties), o.maxProperties)}
+}
+
+// validateDescriptionLength returns an error if the supplied schema has a
+// description that is longer than the options allow.
+func (o *options) validateDescriptionLength(p *field.Path, s *extv1.JSONSchemaProps) field.ErrorList {
+	if o.maxDescriptionLength <= 0 || utf8.RuneCountInString(s.Description) <= o.maxDescriptionLength {
+		return nil
+	}
+	return field.ErrorList{field.TooLong(p.Child("description"), s.Description, o.maxDescriptionLength)}
+}
+
+// validateFormat returns an error if the supplied string schema uses a format
+// that Kubernetes does not support. Unknown formats are silently ignored by the
+// API server, which is rarely what the author of the schema intended.
+func validateFormat(p *field.Path, s *extv1.JSONSchemaProps) field.ErrorList {
+	if s.Type != "string" || s.Format == "" || formats[s.Format] {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(p.Child("format"), s.Format, errUnknownFormat)}
+}
+
+// validateBooleanEnum returns an error if the supplied boolean schema declares
+// an enum, which is redundant at best and is rejected by some tools.
+func validateBooleanEnum(p *field.Path, s *extv1.JSONSchemaProps) field.ErrorList {
+	if s.Type != "boolean" || len(s.Enum) == 0 {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(p.Child("enum"), errBooleanEnum)}
+}
+
+// validateEnum returns an error for each entry of the supplied schema's enum
+// that is not well-formed JSON, or that does not match the schema's type.
+func validateEnum(p *field.Path, s *extv1.JSONSchemaProps) field.ErrorList {
+	errs := field.ErrorList{}
+	for i, e := range s.Enum {
+		var v interface{}
+		if err := json.Unmarshal(e.Raw, &v); err != nil {
+			errs = append(errs, field.Invalid(p.Child("enum").Index(i), string(e.Raw), errMalformedEnum))
+			continue
+		}
+		if !matchesType(v, s) {
+			errs = append(errs, field.Invalid(p.Child("enum").Index(i), string(e.Raw), errEnumType))
+		}
+	}
+	return errs
+}
+
+// matchesType returns true if the supplied unmarshalled JSON value satisfies
+// the supplied schema's type. Any value satisfies a schema with no type.
+func matchesType(v interface{}, s *extv1.JSONSchemaProps) bool {
+	if v == nil {
+		return s.Nullable
+	}
+	switch s.Type {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	}
+	return true
+}
+
+// validateItemBounds returns an error if the supplied schema's minItems or
+// maxItems is negative, or if its maxItems is less than its minItems.
+func validateItemBounds(p *field.Path, s *extv1.JSONSchemaProps) field.ErrorList {
+	errs := field.ErrorList{}
+	if s.MinItems != nil && *s.MinItems < 0 {
+		errs = append(errs, field.Invalid(p.Child("minItems"), *s.MinItems, errNegativeItems))
+	}
+	if s.MaxItems != nil && *s.MaxItems < 0 {
+		errs = append(errs, field.Invalid(p.Child("maxItems"), *s.MaxItems, errNegativeItems))
+	}
+	if s.MinItems != nil && s.MaxItems != nil && *s.MaxItems < *s.MinItems {
+		errs = append(errs, field.Invalid(p.Child("maxItems"), *s.MaxItems, errMaxItemsLessThanMin))
+	}
+	return errs
+}
+
+// validateNumericBounds returns an error if no number could satisfy both the
+// supplied schema's minimum and maximum, accounting for exclusive bounds.
+func validateNumericBounds(p *field.Path, s *extv1.JSONSchemaProps) field.ErrorList {
+	if s.Minimum == nil || s.Maximum == nil {
+		return nil
+	}
+	switch {
+	case *s.Maximum < *s.Minimum:
+		return field.ErrorList{field.Invalid(p.Child("maximum"), *s.Maximum, errMaximumLessThanMin)}
+	case *s.Maximum == *s.Minimum && (s.ExclusiveMinimum || s.ExclusiveMaximum):
+		return field.ErrorList{field.Invalid(p.Child("maximum"), *s.Maximum, errExclusiveBoundEqual)}
+	}
+	return nil
+}
+
+// walkProps calls the supplied function for each property of the supplied
+// schema, and each of their sub-schemas. Properties are walked in a stable
+// order.
+func walkProps(s *extv1.JSONSchemaProps, fn func(p *field.Path, s *extv1.JSONSchemaProps)) {
+	for _, k := range sortedKeys(s.Properties) {
+		prop := s.Properties[k]
+		walk(field.NewPath(k), &prop, fn)
+	}
+}
+
+func walk(p *field.Path, s *extv1.JSONSchemaProps, fn func(p *field.Path, s *extv1.JSONSchemaProps)) {
+	fn(p, s)
+	for _, k := range sortedKeys(s.Properties) {
+		prop := s.Properties[k]
+		walk(p.Child(k), &prop, fn)
+	}
+	if s.Items != nil && s.Items.Schema != nil {
+		walk(p.Key("*"), s.Items.Schema, fn)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		walk(p.Child("*"), s.AdditionalProperties.Schema, fn)
+	}
+}
+
+func sortedKeys(m map[string]extv1.JSONSchemaProps) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
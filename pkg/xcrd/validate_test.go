@@ -0,0 +1,945 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xcrd
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/crossplane/apis/apiextensions/v1beta1"
+)
+
+func TestValidateBatch(t *testing.T) {
+	valid := xrd()
+	conflicting := xrd(func(d *v1beta1.CompositeResourceDefinition) {
+		d.SetName("conflicts.example.org")
+		d.Spec.Names.Plural = "conflicts"
+		d.Spec.ClaimNames.Plural = "conflicts"
+	})
+
+	want := map[string][]error{
+		valid.GetName(): nil,
+		conflicting.GetName(): {
+			errors.Wrap(errors.Wrap(field.ErrorList{
+				field.Invalid(field.NewPath("spec", "claimNames", "plural"), "conflicts", fmt.Sprintf(errFmtConflictingClaimName, "conflicts")),
+			}.ToAggregate(), errInvalidClaimNames), errInvalidClaim),
+		},
+	}
+
+	got := ValidateBatch([]*v1beta1.CompositeResourceDefinition{valid, conflicting})
+	if diff := cmp.Diff(want, got, test.EquateErrors()); diff != "" {
+		t.Errorf("ValidateBatch(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestCheckPluralPrefixes(t *testing.T) {
+	named := func(plural, group string) *v1beta1.CompositeResourceDefinition {
+		return xrd(func(d *v1beta1.CompositeResourceDefinition) {
+			d.SetName(plural + "." + group)
+			d.Spec.Group = group
+			d.Spec.Names.Plural = plural
+			d.Spec.ClaimNames = nil
+		})
+	}
+
+	cases := map[string]struct {
+		reason string
+		xrds   []*v1beta1.CompositeResourceDefinition
+		want   []string
+	}{
+		"SameGroup": {
+			reason: "We should warn when one plural is a prefix of another in the same group.",
+			xrds:   []*v1beta1.CompositeResourceDefinition{named("databases", "example.org"), named("database", "example.org")},
+			want:   []string{fmt.Sprintf(warnFmtPluralPrefix, "database", "databases", "example.org")},
+		},
+		"DifferentGroups": {
+			reason: "We should not warn when one plural is a prefix of another in a different group.",
+			xrds:   []*v1beta1.CompositeResourceDefinition{named("databases", "example.org"), named("database", "example.net")},
+		},
+		"Claim": {
+			reason: "We should warn when a claim plural is a prefix of a composite resource plural in the same group.",
+			xrds:   []*v1beta1.CompositeResourceDefinition{xrd(), named("coolclaimsets", "example.org")},
+			want:   []string{fmt.Sprintf(warnFmtPluralPrefix, "coolclaims", "coolclaimsets", "example.org")},
+		},
+		"Unrelated": {
+			reason: "We should not warn about unrelated plurals.",
+			xrds:   []*v1beta1.CompositeResourceDefinition{xrd(), named("caches", "example.org")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			newOptions(WithWarnings(func(msg string) { got = append(got, msg) })).checkPluralPrefixes(tc.xrds)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckPluralPrefixes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	withSpec := func(spec extv1.JSONSchemaProps) *extv1.CustomResourceDefinition {
+		props := BaseProps()
+		props["spec"] = spec
+		return &extv1.CustomResourceDefinition{
+			Spec: extv1.CustomResourceDefinitionSpec{
+				Versions: []extv1.CustomResourceDefinitionVersion{{
+					Name:    "v1",
+					Storage: true,
+					Schema: &extv1.CustomResourceValidation{
+						OpenAPIV3Schema: &extv1.JSONSchemaProps{Type: "object", Properties: props},
+					},
+				}},
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		crd    *extv1.CustomResourceDefinition
+		want   error
+	}{
+		"EmptySpecNotStrict": {
+			reason: "An empty spec should be allowed when strict validation is disabled.",
+			crd:    withSpec(extv1.JSONSchemaProps{Type: "object"}),
+			want:   nil,
+		},
+		"EmptySpecStrict": {
+			reason: "An empty spec should be rejected when strict validation is enabled.",
+			opts:   []Option{WithStrictValidation()},
+			crd:    withSpec(extv1.JSONSchemaProps{Type: "object"}),
+			want:   errors.Wrapf(errors.New(errEmptySpec), errFmtInvalidVersion, "v1"),
+		},
+		"NoStorageVersion": {
+			reason: "A CRD with no storage version should be rejected.",
+			crd: func() *extv1.CustomResourceDefinition {
+				crd := withSpec(extv1.JSONSchemaProps{Type: "object"})
+				crd.Spec.Versions[0].Storage = false
+				return crd
+			}(),
+			want: errors.Errorf(errFmtStorageVersions, 0, []string{}),
+		},
+		"MultipleStorageVersions": {
+			reason: "A CRD with more than one storage version should be rejected.",
+			crd: func() *extv1.CustomResourceDefinition {
+				crd := withSpec(extv1.JSONSchemaProps{Type: "object"})
+				v2 := crd.Spec.Versions[0]
+				v2.Name = "v2"
+				crd.Spec.Versions = append(crd.Spec.Versions, v2)
+				return crd
+			}(),
+			want: errors.Errorf(errFmtStorageVersions, 2, []string{"v1", "v2"}),
+		},
+		"RootPreservesUnknownFields": {
+			reason: "A CRD whose root preserves unknown fields should be rejected.",
+			crd: func() *extv1.CustomResourceDefinition {
+				crd := withSpec(extv1.JSONSchemaProps{Type: "object"})
+				preserve := true
+				crd.Spec.Versions[0].Schema.OpenAPIV3Schema.XPreserveUnknownFields = &preserve
+				return crd
+			}(),
+			want: errors.Wrapf(errors.New(errRootPreserveUnknown), errFmtInvalidVersion, "v1"),
+		},
+		"TooManyPrinterColumns": {
+			reason: "A version with more printer columns than the configured maximum should be rejected.",
+			opts:   []Option{WithMaxPrinterColumns(1)},
+			crd: func() *extv1.CustomResourceDefinition {
+				crd := withSpec(extv1.JSONSchemaProps{Type: "object"})
+				crd.Spec.Versions[0].AdditionalPrinterColumns = CompositeResourcePrinterColumns()
+				return crd
+			}(),
+			want: errors.Wrapf(errors.Errorf(errFmtTooManyColumns, 2, 1), errFmtInvalidVersion, "v1"),
+		},
+		"UnsupportedKeyword": {
+			reason: "A schema using a keyword CustomResourceDefinitions do not support should be rejected.",
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"tags": {
+						Type: "object",
+						PatternProperties: map[string]extv1.JSONSchemaProps{
+							"^[a-z]+$": {Type: "string"},
+						},
+					},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.Forbidden(field.NewPath("spec", "tags", "patternProperties"), errUnsupportedKeyword),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"TooManyProperties": {
+			reason: "An object with more properties than the configured maximum should be rejected.",
+			opts:   []Option{WithMaxProperties(2)},
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"a": {Type: "string"},
+					"b": {Type: "string"},
+					"c": {Type: "string"},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.TooMany(field.NewPath("spec", "properties"), 3, 2),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"DescriptionTooLong": {
+			reason: "A description longer than the configured maximum should be rejected.",
+			opts:   []Option{WithMaxDescriptionLength(10, false)},
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"a": {Type: "string", Description: "Short."},
+					"b": {Type: "string", Description: "Far too long."},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.TooLong(field.NewPath("spec", "b", "description"), "Far too long.", 10),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"RootDescriptionTooLong": {
+			reason: "A root description longer than the configured maximum should be rejected.",
+			opts:   []Option{WithMaxDescriptionLength(10, false)},
+			crd: func() *extv1.CustomResourceDefinition {
+				crd := withSpec(extv1.JSONSchemaProps{Type: "object"})
+				crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Description = "Far too long."
+				return crd
+			}(),
+			want: errors.Wrapf(field.ErrorList{
+				field.TooLong(field.NewPath("description"), "Far too long.", 10),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"UnknownFormatStrict": {
+			reason: "A string property with a format Kubernetes does not support should be rejected.",
+			opts:   []Option{WithStrictValidation()},
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"email":   {Type: "string", Format: "email"},
+					"address": {Type: "string", Format: "ip-address"},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.Invalid(field.NewPath("spec", "address", "format"), "ip-address", errUnknownFormat),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"BooleanEnumStrict": {
+			reason: "A boolean property that declares an enum should be rejected.",
+			opts:   []Option{WithStrictValidation()},
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"enabled": {Type: "boolean", Enum: []extv1.JSON{{Raw: []byte("true")}}},
+					"size":    {Type: "string", Enum: []extv1.JSON{{Raw: []byte(`"small"`)}}},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.Forbidden(field.NewPath("spec", "enabled", "enum"), errBooleanEnum),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"ItemBoundsStrict": {
+			reason: "An array whose maxItems is less than its minItems should be rejected.",
+			opts:   []Option{WithStrictValidation()},
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"zones": {
+						Type:     "array",
+						MinItems: func() *int64 { i := int64(3); return &i }(),
+						MaxItems: func() *int64 { i := int64(1); return &i }(),
+						Items:    &extv1.JSONSchemaPropsOrArray{Schema: &extv1.JSONSchemaProps{Type: "string"}},
+					},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.Invalid(field.NewPath("spec", "zones", "maxItems"), int64(1), errMaxItemsLessThanMin),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"EnumType": {
+			reason: "An enum entry that does not match the type of its property should be rejected.",
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"engineVersion": {
+						Type: "string",
+						Enum: []extv1.JSON{{Raw: []byte(`"5.6"`)}, {Raw: []byte(`5.7`)}},
+					},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.Invalid(field.NewPath("spec", "engineVersion", "enum").Index(1), "5.7", errEnumType),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"MalformedEnum": {
+			reason: "An enum entry that is not well-formed JSON should be rejected.",
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"engineVersion": {
+						Type: "string",
+						Enum: []extv1.JSON{{Raw: []byte(`"5.6`)}},
+					},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.Invalid(field.NewPath("spec", "engineVersion", "enum").Index(0), `"5.6`, errMalformedEnum),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"NumericBoundsStrict": {
+			reason: "A number whose maximum is less than its minimum should be rejected.",
+			opts:   []Option{WithStrictValidation()},
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"storageGB": {
+						Type:    "integer",
+						Minimum: func() *float64 { f := float64(100); return &f }(),
+						Maximum: func() *float64 { f := float64(10); return &f }(),
+					},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.Invalid(field.NewPath("spec", "storageGB", "maximum"), float64(10), errMaximumLessThanMin),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"ExclusiveNumericBoundsStrict": {
+			reason: "A number whose equal minimum and maximum are exclusive should be rejected.",
+			opts:   []Option{WithStrictValidation()},
+			crd: withSpec(extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"replicas": {
+						Type:             "integer",
+						Minimum:          func() *float64 { f := float64(3); return &f }(),
+						Maximum:          func() *float64 { f := float64(3); return &f }(),
+						ExclusiveMinimum: true,
+					},
+				},
+			}),
+			want: errors.Wrapf(field.ErrorList{
+				field.Invalid(field.NewPath("spec", "replicas", "maximum"), float64(3), errExclusiveBoundEqual),
+			}.ToAggregate(), errFmtInvalidVersion, "v1"),
+		},
+		"ManagedSpecStrict": {
+			reason: "A spec containing only the props Crossplane manages should be allowed.",
+			opts:   []Option{WithStrictValidation()},
+			crd:    withSpec(extv1.JSONSchemaProps{Type: "object", Properties: CompositeResourceSpecProps()}),
+			want:   nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := newOptions(tc.opts...).validate(tc.crd)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nvalidate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckConversion(t *testing.T) {
+	version := func(name string, props map[string]extv1.JSONSchemaProps) extv1.CustomResourceDefinitionVersion {
+		return extv1.CustomResourceDefinitionVersion{
+			Name:   name,
+			Served: true,
+			Schema: &extv1.CustomResourceValidation{
+				OpenAPIV3Schema: &extv1.JSONSchemaProps{Type: "object", Properties: props},
+			},
+		}
+	}
+	a := map[string]extv1.JSONSchemaProps{"a": {Type: "string"}}
+	b := map[string]extv1.JSONSchemaProps{"b": {Type: "string"}}
+
+	cases := map[string]struct {
+		reason string
+		crd    *extv1.CustomResourceDefinition
+		want   []string
+	}{
+		"IdenticalSchemas": {
+			reason: "We should not warn when all served versions have identical schemas.",
+			crd: &extv1.CustomResourceDefinition{
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Versions: []extv1.CustomResourceDefinitionVersion{version("v1", a), version("v2", a)},
+				},
+			},
+		},
+		"DivergentSchemasNoConversion": {
+			reason: "We should warn when served versions have divergent schemas and the None conversion strategy is used.",
+			crd: &extv1.CustomResourceDefinition{
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Versions:   []extv1.CustomResourceDefinitionVersion{version("v1", a), version("v2", b)},
+					Conversion: &extv1.CustomResourceConversion{Strategy: extv1.NoneConverter},
+				},
+			},
+			want: []string{fmt.Sprintf(warnFmtDivergentSchemas, "v1", "v2")},
+		},
+		"DivergentSchemasWebhookConversion": {
+			reason: "We should not warn when served versions have divergent schemas but a conversion webhook is configured.",
+			crd: &extv1.CustomResourceDefinition{
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Versions:   []extv1.CustomResourceDefinitionVersion{version("v1", a), version("v2", b)},
+					Conversion: &extv1.CustomResourceConversion{Strategy: extv1.WebhookConverter},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			newOptions(WithWarnings(func(msg string) { got = append(got, msg) })).checkConversion(tc.crd)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckConversion(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckNames(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		n      extv1.CustomResourceDefinitionNames
+		want   []string
+	}{
+		"Conventional": {
+			reason: "We should not warn when the singular name is the lowercase of the kind.",
+			n:      extv1.CustomResourceDefinitionNames{Kind: "CoolComposite", Singular: "coolcomposite"},
+		},
+		"DefaultedSingular": {
+			reason: "We should not warn when the singular name is omitted, since the API server will default it.",
+			n:      extv1.CustomResourceDefinitionNames{Kind: "CoolComposite"},
+		},
+		"Unconventional": {
+			reason: "We should warn when the singular name is not the lowercase of the kind.",
+			n:      extv1.CustomResourceDefinitionNames{Kind: "CoolComposite", Singular: "cool"},
+			want:   []string{fmt.Sprintf(warnFmtUnconventionalSingular, "cool", "CoolComposite")},
+		},
+		"ConventionalPlurals": {
+			reason: "We should not warn when the plural name is the plural of the singular name.",
+			n:      extv1.CustomResourceDefinitionNames{Kind: "Policy", Singular: "policy", Plural: "policies"},
+		},
+		"DefaultedSingularPlural": {
+			reason: "We should compare the plural name to the plural of the lowercase kind when the singular name is omitted.",
+			n:      extv1.CustomResourceDefinitionNames{Kind: "Gateway", Plural: "gateways"},
+		},
+		"UnconventionalPlural": {
+			reason: "We should warn when the plural name is not the plural of the singular name.",
+			n:      extv1.CustomResourceDefinitionNames{Kind: "Database", Singular: "database", Plural: "databasez"},
+			want:   []string{fmt.Sprintf(warnFmtUnconventionalPlural, "databasez", "database", "databases")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			newOptions(WithWarnings(func(msg string) { got = append(got, msg) })).checkNames(tc.n)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckNames(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidateVersionNames(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		name   string
+		want   error
+	}{
+		"GA": {
+			reason: "A GA version should be valid.",
+			name:   "v1",
+		},
+		"Alpha": {
+			reason: "An alpha version should be valid.",
+			name:   "v1alpha1",
+		},
+		"Beta": {
+			reason: "A beta version should be valid.",
+			name:   "v12beta3",
+		},
+		"NotKubeLike": {
+			reason: "A version that is not intended to be a Kubernetes API version should be valid.",
+			name:   "foo1",
+		},
+		"MissingMinor": {
+			reason: "An alpha version without a minor version should be rejected.",
+			name:   "v1alpha",
+			want:   errors.Errorf(errFmtVersionName, "v1alpha"),
+		},
+		"UnknownStability": {
+			reason: "A version with an unknown stability level should be rejected.",
+			name:   "v1gamma1",
+			want:   errors.Errorf(errFmtVersionName, "v1gamma1"),
+		},
+		"ZeroMajor": {
+			reason: "A version with a zero major version should be rejected.",
+			name:   "v0",
+			want:   errors.Errorf(errFmtVersionName, "v0"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd := &extv1.CustomResourceDefinition{Spec: extv1.CustomResourceDefinitionSpec{
+				Versions: []extv1.CustomResourceDefinitionVersion{{Name: tc.name}},
+			}}
+			err := validateVersionNames(crd)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nvalidateVersionNames(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckSwappedNames(t *testing.T) {
+	type args struct {
+		xr    extv1.CustomResourceDefinitionNames
+		claim extv1.CustomResourceDefinitionNames
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []string
+	}{
+		"Conventional": {
+			reason: "We should not warn when each singular name derives from its own kind.",
+			args: args{
+				xr:    extv1.CustomResourceDefinitionNames{Kind: "XPostgreSQLInstance", Singular: "xpostgresqlinstance"},
+				claim: extv1.CustomResourceDefinitionNames{Kind: "PostgreSQLInstance", Singular: "postgresqlinstance"},
+			},
+		},
+		"Shortened": {
+			reason: "We should not warn when a singular name derives from neither kind.",
+			args: args{
+				xr:    extv1.CustomResourceDefinitionNames{Kind: "CoolComposite", Singular: "cool"},
+				claim: extv1.CustomResourceDefinitionNames{Kind: "CoolClaim", Singular: "coolclaim"},
+			},
+		},
+		"Swapped": {
+			reason: "We should warn when each singular name derives from the other's kind.",
+			args: args{
+				xr:    extv1.CustomResourceDefinitionNames{Kind: "CoolComposite", Singular: "coolclaim"},
+				claim: extv1.CustomResourceDefinitionNames{Kind: "CoolClaim", Singular: "coolcomposite"},
+			},
+			want: []string{
+				fmt.Sprintf(warnFmtSwappedSingular, "coolclaim", "composite resource", "CoolClaim", "claim"),
+				fmt.Sprintf(warnFmtSwappedSingular, "coolcomposite", "claim", "CoolComposite", "composite resource"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			newOptions(WithWarnings(func(msg string) { got = append(got, msg) })).checkSwappedNames(tc.args.xr, tc.args.claim)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckSwappedNames(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaRoot(t *testing.T) {
+	type want struct {
+		err      error
+		warnings []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		schema string
+		opts   []Option
+		want   want
+	}{
+		"NoAdditionalProperties": {
+			reason: "A schema that does not set additionalProperties at its root is valid.",
+			schema: `{"properties":{"spec":{"properties":{"a":{"type":"string"}}}}}`,
+		},
+		"NestedAdditionalProperties": {
+			reason: "A schema may set additionalProperties below its root.",
+			schema: `{"properties":{"spec":{"properties":{"a":{"type":"object","additionalProperties":{"type":"string"}}}}}}`,
+		},
+		"RootAdditionalPropertiesFalse": {
+			reason: "We should warn that additionalProperties set at the root of the schema is ignored.",
+			schema: `{"additionalProperties":false,"properties":{"spec":{"properties":{"a":{"type":"string"}}}}}`,
+			want: want{
+				warnings: []string{fmt.Sprintf(warnFmtIgnoredRootAdditional, "v1beta1")},
+			},
+		},
+		"RootAdditionalPropertiesSchemaStrict": {
+			reason: "Setting additionalProperties at the root of the schema is an error when validation is strict.",
+			schema: `{"additionalProperties":{"type":"string"},"properties":{"spec":{"properties":{"a":{"type":"string"}}}}}`,
+			opts:   []Option{WithStrictValidation()},
+			want: want{
+				err: errors.Wrapf(errors.New(errRootAdditionalProps), errFmtInvalidVersion, "v1beta1"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var warnings []string
+			opts := append([]Option{WithWarnings(func(msg string) { warnings = append(warnings, msg) })}, tc.opts...)
+			crd, err := ForCompositeResource(xrd(withSchema(tc.schema)), opts...)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.warnings, warnings); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want warnings, +got warnings:\n%s", tc.reason, diff)
+			}
+			if err == nil && crd.Spec.Versions[0].Schema.OpenAPIV3Schema.AdditionalProperties != nil {
+				t.Errorf("\n%s\nForCompositeResource(...): root of the schema must not set additionalProperties", tc.reason)
+			}
+		})
+	}
+}
+
+func TestValidatePrinterColumns(t *testing.T) {
+	version := func(cols ...extv1.CustomResourceColumnDefinition) extv1.CustomResourceDefinitionVersion {
+		props := BaseProps()
+		props["spec"] = extv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]extv1.JSONSchemaProps{
+				"replicas": {Type: "integer"},
+				"labels":   {Type: "object", AdditionalProperties: &extv1.JSONSchemaPropsOrBool{Schema: &extv1.JSONSchemaProps{Type: "string"}}},
+			},
+		}
+		props["status"] = extv1.JSONSchemaProps{Type: "object", Properties: CompositeResourceStatusProps()}
+		return extv1.CustomResourceDefinitionVersion{
+			Name:                     "v1",
+			AdditionalPrinterColumns: cols,
+			Schema: &extv1.CustomResourceValidation{
+				OpenAPIV3Schema: &extv1.JSONSchemaProps{Type: "object", Properties: props},
+			},
+			Subresources: &extv1.CustomResourceSubresources{
+				Status: &extv1.CustomResourceSubresourceStatus{},
+			},
+		}
+	}
+	withoutStatus := func(vr extv1.CustomResourceDefinitionVersion) extv1.CustomResourceDefinitionVersion {
+		vr.Subresources = nil
+		return vr
+	}
+	col := func(name, typ, path string) extv1.CustomResourceColumnDefinition {
+		return extv1.CustomResourceColumnDefinition{Name: name, Type: typ, JSONPath: path}
+	}
+	p := field.NewPath("additionalPrinterColumns")
+
+	cases := map[string]struct {
+		reason string
+		strict bool
+		vr     extv1.CustomResourceDefinitionVersion
+		want   field.ErrorList
+	}{
+		"Valid": {
+			reason: "Columns with valid types and formats whose paths resolve should be allowed.",
+			vr: version(
+				extv1.CustomResourceColumnDefinition{Name: "REPLICAS", Type: "integer", Format: "int32", Priority: 1, JSONPath: ".spec.replicas"},
+				col("READY", "string", ".status.conditions[?(@.type=='Ready')].status"),
+				col("TEAM", "string", ".spec.labels.team"),
+				col("NAME", "string", ".metadata.name"),
+			),
+			want: field.ErrorList{},
+		},
+		"Invalid": {
+			reason: "Columns with unsupported types or formats, negative priorities, or unresolvable paths should be rejected.",
+			vr: version(
+				col("COUNT", "int", ".spec.replicas"),
+				extv1.CustomResourceColumnDefinition{Name: "REPLICAS", Type: "integer", Format: "uint8", Priority: -1, JSONPath: ".spec.replicas"},
+				col("SIZE", "string", ".spec.size"),
+				col("DEEP", "string", ".spec.replicas.deep"),
+			),
+			want: field.ErrorList{
+				field.NotSupported(p.Index(0).Child("type"), "int", columnTypes),
+				field.NotSupported(p.Index(1).Child("format"), "uint8", columnFormats),
+				field.Invalid(p.Index(1).Child("priority"), int32(-1), errNegativePriority),
+				field.Invalid(p.Index(2).Child("jsonPath"), ".spec.size", errUnresolvedJSONPath),
+				field.Invalid(p.Index(3).Child("jsonPath"), ".spec.replicas.deep", errUnresolvedJSONPath),
+			},
+		},
+		"EmptyJSONPath": {
+			reason: "Columns must have a JSONPath.",
+			vr:     version(col("REPLICAS", "integer", "")),
+			want: field.ErrorList{
+				field.Required(p.Index(0).Child("jsonPath"), ""),
+			},
+		},
+		"DuplicateName": {
+			reason: "Columns must have unique names.",
+			vr:     version(col("REPLICAS", "integer", ".spec.replicas"), col("REPLICAS", "integer", ".spec.replicas")),
+			want: field.ErrorList{
+				field.Duplicate(p.Index(1).Child("name"), "REPLICAS"),
+			},
+		},
+		"StatusColumnWithoutStatusSubresource": {
+			reason: "Columns should not refer to the status when the status subresource is disabled.",
+			vr:     withoutStatus(version(col("REPLICAS", "integer", ".spec.replicas"), col("READY", "string", ".status.conditions[?(@.type=='Ready')].status"))),
+			want: field.ErrorList{
+				field.Invalid(p.Index(1).Child("jsonPath"), ".status.conditions[?(@.type=='Ready')].status", errStatusColumn),
+			},
+		},
+		"LowercaseNameNotStrict": {
+			reason: "Lowercase column names should be allowed when strict validation is disabled.",
+			vr:     version(col("replicas", "integer", ".spec.replicas")),
+			want:   field.ErrorList{},
+		},
+		"LowercaseNameStrict": {
+			reason: "Lowercase column names should be rejected when strict validation is enabled.",
+			strict: true,
+			vr:     version(col("replicas", "integer", ".spec.replicas")),
+			want: field.ErrorList{
+				field.Invalid(p.Index(0).Child("name"), "replicas", errLowercaseColumnName),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := newOptions()
+			o.strict = tc.strict
+			got := o.validatePrinterColumns(tc.vr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nvalidatePrinterColumns(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckColumnPaths(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		cols   []extv1.CustomResourceColumnDefinition
+		want   []string
+	}{
+		"Defaults": {
+			reason: "We should not warn about the default columns, which have distinct JSONPaths.",
+			cols:   CompositeResourcePrinterColumns(),
+		},
+		"DuplicatePath": {
+			reason: "We should warn when a column has the same JSONPath as another.",
+			cols: append(CompositeResourcePrinterColumns(), extv1.CustomResourceColumnDefinition{
+				Name:     "SYNCED",
+				Type:     "string",
+				JSONPath: ".status.conditions[?(@.type=='Ready')].status",
+			}),
+			want: []string{fmt.Sprintf(warnFmtDuplicateColumnPath, "READY", "SYNCED", "v1", ".status.conditions[?(@.type=='Ready')].status")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			vr := extv1.CustomResourceDefinitionVersion{Name: "v1", AdditionalPrinterColumns: tc.cols}
+			newOptions(WithWarnings(func(msg string) { got = append(got, msg) })).checkColumnPaths(vr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckColumnPaths(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidateConversions(t *testing.T) {
+	version := func(name string, props map[string]extv1.JSONSchemaProps) extv1.CustomResourceDefinitionVersion {
+		return extv1.CustomResourceDefinitionVersion{
+			Name:   name,
+			Served: true,
+			Schema: &extv1.CustomResourceValidation{
+				OpenAPIV3Schema: &extv1.JSONSchemaProps{Type: "object", Properties: props},
+			},
+		}
+	}
+	a := map[string]extv1.JSONSchemaProps{"a": {Type: "string"}}
+	b := map[string]extv1.JSONSchemaProps{"b": {Type: "string"}}
+	webhook := &extv1.CustomResourceConversion{Strategy: extv1.WebhookConverter}
+
+	cases := map[string]struct {
+		reason      string
+		conversions []VersionConversion
+		crd         *extv1.CustomResourceDefinition
+		want        error
+	}{
+		"IdenticalSchemas": {
+			reason: "Served versions with identical schemas need no conversion.",
+			crd: &extv1.CustomResourceDefinition{
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Versions: []extv1.CustomResourceDefinitionVersion{version("v1", a), version("v2", a)},
+				},
+			},
+		},
+		"UndeclaredConversion": {
+			reason: "Served versions with divergent schemas need a declared conversion.",
+			crd: &extv1.CustomResourceDefinition{
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Versions:   []extv1.CustomResourceDefinitionVersion{version("v1", a), version("v2", a), version("v3", b)},
+					Conversion: webhook,
+				},
+			},
+			conversions: []VersionConversion{{From: "v2", To: "v3"}},
+			want:        errors.Errorf(errFmtUndeclaredConversion, "v1", "v3"),
+		},
+		"NoConversionWebhook": {
+			reason: "Served versions with divergent schemas need a conversion webhook.",
+			crd: &extv1.CustomResourceDefinition{
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Versions: []extv1.CustomResourceDefinitionVersion{version("v1", a), version("v2", b)},
+				},
+			},
+			conversions: []VersionConversion{{From: "v1", To: "v2"}},
+			want:        errors.Errorf(errFmtNoConversionWebhook, "v1", "v2"),
+		},
+		"DeclaredConversion": {
+			reason: "Served versions with divergent schemas are valid if a conversion is declared and a webhook is configured.",
+			crd: &extv1.CustomResourceDefinition{
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Versions:   []extv1.CustomResourceDefinitionVersion{version("v1", a), version("v2", b)},
+					Conversion: webhook,
+				},
+			},
+			conversions: []VersionConversion{{From: "v2", To: "v1"}},
+		},
+		"UnknownVersion": {
+			reason: "Declared conversions must reference known versions.",
+			crd: &extv1.CustomResourceDefinition{
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Versions: []extv1.CustomResourceDefinitionVersion{version("v1", a)},
+				},
+			},
+			conversions: []VersionConversion{{From: "v1", To: "v1"}, {From: "v1", To: "v9"}},
+			want:        errors.Errorf(errFmtUnknownConversionVersion, "v9"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := newOptions(WithVersionConversions(tc.conversions...)).validateConversions(tc.crd)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nvalidateConversions(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckRepeatedSchemas(t *testing.T) {
+	endpoint := extv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]extv1.JSONSchemaProps{
+			"host": {Type: "string"},
+			"port": {Type: "integer"},
+		},
+	}
+	raw, _ := json.Marshal(endpoint)
+	size := len(raw)
+
+	version := func(spec map[string]extv1.JSONSchemaProps) extv1.CustomResourceDefinitionVersion {
+		return extv1.CustomResourceDefinitionVersion{
+			Name: "v1",
+			Schema: &extv1.CustomResourceValidation{
+				OpenAPIV3Schema: &extv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"spec": {Type: "object", Properties: spec},
+					},
+				},
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		vr     extv1.CustomResourceDefinitionVersion
+		want   []string
+	}{
+		"NoRepetition": {
+			reason: "We should not warn when no object schema is repeated.",
+			vr: version(map[string]extv1.JSONSchemaProps{
+				"primary": endpoint,
+				"name":    {Type: "string"},
+				"port":    {Type: "string"},
+			}),
+		},
+		"Repetition": {
+			reason: "We should warn when an object schema is repeated.",
+			vr: version(map[string]extv1.JSONSchemaProps{
+				"primary": endpoint,
+				"replicas": {
+					Type:  "array",
+					Items: &extv1.JSONSchemaPropsOrArray{Schema: &endpoint},
+				},
+			}),
+			want: []string{fmt.Sprintf(warnFmtRepeatedSchema, "v1", size, 2, size*2, "spec.primary, spec.replicas[*]")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			newOptions(WithWarnings(func(msg string) { got = append(got, msg) })).checkRepeatedSchemas(tc.vr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckRepeatedSchemas(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckGroup(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		group  string
+		want   []string
+	}{
+		"VendorGroup": {
+			reason: "We should not warn about a group within a vendor's domain.",
+			group:  "database.example.org",
+		},
+		"CoreGroup": {
+			reason: "We should warn about a built-in Kubernetes group.",
+			group:  "apps",
+			want:   []string{fmt.Sprintf(warnFmtReservedGroup, "apps", "apps.example.org")},
+		},
+		"KubernetesDomain": {
+			reason: "We should warn about a group within the k8s.io domain.",
+			group:  "databases.k8s.io",
+			want:   []string{fmt.Sprintf(warnFmtReservedGroup, "databases.k8s.io", "databases.example.org")},
+		},
+		"KubernetesDomainLookalike": {
+			reason: "We should not warn about a group that merely ends with k8s.io.",
+			group:  "notk8s.io",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			newOptions(WithWarnings(func(msg string) { got = append(got, msg) })).checkGroup(tc.group)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckGroup(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xcrd
+
+import (
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const errMarshalYAML = "cannot marshal CustomResourceDefinition to YAML"
+
+// ToYAML renders the supplied CustomResourceDefinition as YAML. The apiVersion
+// and kind of the CustomResourceDefinition are set if they are empty. Fields
+// are rendered in a stable order, so rendering the same CustomResourceDefinition
+// twice produces the same YAML.
+func ToYAML(crd *extv1.CustomResourceDefinition) ([]byte, error) {
+	c := crd.DeepCopy()
+	if c.APIVersion == "" {
+		c.APIVersion = extv1.SchemeGroupVersion.String()
+	}
+	if c.Kind == "" {
+		c.Kind = "CustomResourceDefinition"
+	}
+	out, err := yaml.Marshal(c)
+	return out, errors.Wrap(err, errMarshalYAML)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xcrd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestToYAML(t *testing.T) {
+	schema := `{"properties":{"spec":{"properties":{"size":{"type":"string","enum":["small","large"],"default":"small"}}}}}`
+	crd, err := ForCompositeResource(xrd(withSchema(schema)))
+	if err != nil {
+		t.Fatalf("ForCompositeResource(...): %s", err)
+	}
+
+	out, err := ToYAML(crd)
+	if err != nil {
+		t.Fatalf("ToYAML(...): %s", err)
+	}
+
+	again, err := ToYAML(crd)
+	if err != nil {
+		t.Fatalf("ToYAML(...): %s", err)
+	}
+	if diff := cmp.Diff(string(out), string(again)); diff != "" {
+		t.Errorf("ToYAML(...): rendering should be stable: -first, +second:\n%s", diff)
+	}
+
+	got := &extv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(out, got); err != nil {
+		t.Fatalf("yaml.Unmarshal(...): %s", err)
+	}
+
+	want := crd.DeepCopy()
+	want.APIVersion = extv1.SchemeGroupVersion.String()
+	want.Kind = "CustomResourceDefinition"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ToYAML(...): -want, +got:\n%s", diff)
+	}
+	if crd.Kind != "" {
+		t.Errorf("ToYAML(...): must not mutate the supplied CustomResourceDefinition")
+	}
+}